@@ -0,0 +1,40 @@
+package ast
+
+import (
+	"bytes"
+	"github.com/jacksonopp/monkey/token"
+	"strings"
+)
+
+// ArrayLiteral
+// ex: `[1, 2 * 2, fn(x) { x }]`
+type ArrayLiteral struct {
+	Token    token.Token // token.LBRACKET
+	Elements []Expression
+}
+
+func (a ArrayLiteral) TokenLiteral() string {
+	return a.Token.Literal
+}
+
+func (a ArrayLiteral) Pos() token.Position {
+	return a.Token.Pos
+}
+
+func (a ArrayLiteral) String() string {
+	var out bytes.Buffer
+
+	elements := []string{}
+	for _, e := range a.Elements {
+		elements = append(elements, e.String())
+	}
+
+	out.WriteString("[")
+	out.WriteString(strings.Join(elements, ", "))
+	out.WriteString("]")
+
+	return out.String()
+}
+
+func (a ArrayLiteral) expressionNode() {
+}
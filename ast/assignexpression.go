@@ -0,0 +1,40 @@
+package ast
+
+import (
+	"bytes"
+	"github.com/jacksonopp/monkey/token"
+)
+
+// AssignExpression updates an existing binding or element
+// ex: `x = 5`, `x += 1` or `arr[0] = 2`
+type AssignExpression struct {
+	Token    token.Token // the assignment operator token
+	Target   Expression  // an *Identifier or *IndexExpression
+	Operator string      // "=", or a compound operator such as "+="
+	Value    Expression
+}
+
+func (a AssignExpression) TokenLiteral() string {
+	return a.Token.Literal
+}
+
+func (a AssignExpression) Pos() token.Position {
+	return a.Token.Pos
+}
+
+func (a AssignExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(a.Target.String())
+	out.WriteString(" ")
+	out.WriteString(a.Operator)
+	out.WriteString(" ")
+	out.WriteString(a.Value.String())
+	out.WriteString(")")
+
+	return out.String()
+}
+
+func (a AssignExpression) expressionNode() {
+}
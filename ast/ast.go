@@ -1,9 +1,12 @@
 package ast
 
+import "github.com/jacksonopp/monkey/token"
+
 // Node is the root interface that all AST nodes implement
 type Node interface {
 	TokenLiteral() string
 	String() string
+	Pos() token.Position // the position of the token that introduced the node
 }
 
 // Statement is a unit of execution
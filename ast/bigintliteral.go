@@ -0,0 +1,28 @@
+package ast
+
+import (
+	"github.com/jacksonopp/monkey/token"
+	"math/big"
+)
+
+// BigIntLiteral is an integer literal too large to fit in an int64
+// ex: `99999999999999999999`
+type BigIntLiteral struct {
+	Token token.Token // token.INT
+	Value *big.Int
+}
+
+func (b BigIntLiteral) TokenLiteral() string {
+	return b.Token.Literal
+}
+
+func (b BigIntLiteral) Pos() token.Position {
+	return b.Token.Pos
+}
+
+func (b BigIntLiteral) String() string {
+	return b.TokenLiteral()
+}
+
+func (b BigIntLiteral) expressionNode() {
+}
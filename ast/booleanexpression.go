@@ -11,6 +11,10 @@ func (b Boolean) TokenLiteral() string {
 	return b.Token.Literal
 }
 
+func (b Boolean) Pos() token.Position {
+	return b.Token.Pos
+}
+
 func (b Boolean) String() string {
 	return b.TokenLiteral()
 }
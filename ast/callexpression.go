@@ -10,12 +10,17 @@ type CallExpression struct {
 	Token     token.Token
 	Function  Expression
 	Arguments []Expression
+	Tail      bool // whether the call is the last thing its function does, so its result is the function's
 }
 
 func (c CallExpression) TokenLiteral() string {
 	return c.Token.Literal
 }
 
+func (c CallExpression) Pos() token.Position {
+	return c.Token.Pos
+}
+
 func (c CallExpression) String() string {
 	var out bytes.Buffer
 
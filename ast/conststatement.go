@@ -0,0 +1,39 @@
+package ast
+
+import (
+	"bytes"
+	"github.com/jacksonopp/monkey/token"
+)
+
+// ConstStatement binds a name that cannot be assigned to or declared again
+// ex: `const x = 3;`
+type ConstStatement struct {
+	Token token.Token // token.CONST
+	Name  *Identifier // Identifier of the binding (x)
+	Value Expression  // Expression that produces the value (3)
+}
+
+func (cs *ConstStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(cs.TokenLiteral() + " ")
+	out.WriteString(cs.Name.String())
+	out.WriteString(" = ")
+
+	if cs.Value != nil {
+		out.WriteString(cs.Value.String())
+	}
+
+	out.WriteString(";")
+
+	return out.String()
+}
+
+func (cs *ConstStatement) statementNode() {}
+func (cs *ConstStatement) TokenLiteral() string {
+	return cs.Token.Literal
+}
+
+func (cs *ConstStatement) Pos() token.Position {
+	return cs.Token.Pos
+}
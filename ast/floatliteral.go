@@ -0,0 +1,25 @@
+package ast
+
+import "github.com/jacksonopp/monkey/token"
+
+// FloatLiteral
+// ex: `3.14` or `1e-9`
+type FloatLiteral struct {
+	Token token.Token // token.FLOAT
+	Value float64
+}
+
+func (f FloatLiteral) TokenLiteral() string {
+	return f.Token.Literal
+}
+
+func (f FloatLiteral) Pos() token.Position {
+	return f.Token.Pos
+}
+
+func (f FloatLiteral) String() string {
+	return f.TokenLiteral()
+}
+
+func (f FloatLiteral) expressionNode() {
+}
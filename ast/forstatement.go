@@ -0,0 +1,37 @@
+package ast
+
+import (
+	"bytes"
+	"github.com/jacksonopp/monkey/token"
+)
+
+// ForStatement
+// ex: `for (x in [1, 2, 3]) { puts(x); }`
+type ForStatement struct {
+	Token    token.Token // token.FOR
+	Variable *Identifier // bound to each element in turn (x)
+	Iterable Expression  // the array, string or hash being looped over
+	Body     *BlockStatement
+}
+
+func (fs *ForStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("for (")
+	out.WriteString(fs.Variable.String())
+	out.WriteString(" in ")
+	out.WriteString(fs.Iterable.String())
+	out.WriteString(") ")
+	out.WriteString(fs.Body.String())
+
+	return out.String()
+}
+
+func (fs *ForStatement) statementNode() {}
+func (fs *ForStatement) TokenLiteral() string {
+	return fs.Token.Literal
+}
+
+func (fs *ForStatement) Pos() token.Position {
+	return fs.Token.Pos
+}
@@ -9,6 +9,8 @@ import (
 type FunctionLiteral struct {
 	Token      token.Token
 	Parameters []*Identifier
+	Defaults   []Expression // the default value of each parameter, nil where it has none
+	Rest       *Identifier  // the ...rest parameter collecting extra arguments, if any
 	Body       *BlockStatement
 }
 
@@ -16,23 +18,40 @@ func (f FunctionLiteral) TokenLiteral() string {
 	return f.Token.Literal
 }
 
+func (f FunctionLiteral) Pos() token.Position {
+	return f.Token.Pos
+}
+
 func (f FunctionLiteral) String() string {
 	var out bytes.Buffer
 
-	params := []string{}
-	for _, p := range f.Parameters {
-		params = append(params, p.String())
-	}
-
 	out.WriteString(f.TokenLiteral())
 	out.WriteString("(")
-	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(ParameterList(f.Parameters, f.Defaults, f.Rest))
 	out.WriteString(")")
 	out.WriteString(f.Body.String())
 
 	return out.String()
 }
 
+// ParameterList formats parameters the way they are written in a function literal, along
+// with their default values and the rest parameter.
+func ParameterList(params []*Identifier, defaults []Expression, rest *Identifier) string {
+	list := []string{}
+	for i, p := range params {
+		if i < len(defaults) && defaults[i] != nil {
+			list = append(list, p.String()+" = "+defaults[i].String())
+		} else {
+			list = append(list, p.String())
+		}
+	}
+	if rest != nil {
+		list = append(list, "..."+rest.String())
+	}
+
+	return strings.Join(list, ", ")
+}
+
 func (f FunctionLiteral) expressionNode() {
 
 }
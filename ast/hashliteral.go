@@ -0,0 +1,45 @@
+package ast
+
+import (
+	"bytes"
+	"github.com/jacksonopp/monkey/token"
+	"strings"
+)
+
+// HashLiteral
+// ex: `{"one": 1, 2: true}`
+type HashLiteral struct {
+	Token token.Token // token.LBRACE
+	Pairs []HashPair  // the pairs in the order they were written
+}
+
+type HashPair struct {
+	Key   Expression
+	Value Expression
+}
+
+func (h HashLiteral) TokenLiteral() string {
+	return h.Token.Literal
+}
+
+func (h HashLiteral) Pos() token.Position {
+	return h.Token.Pos
+}
+
+func (h HashLiteral) String() string {
+	var out bytes.Buffer
+
+	pairs := []string{}
+	for _, pair := range h.Pairs {
+		pairs = append(pairs, pair.Key.String()+": "+pair.Value.String())
+	}
+
+	out.WriteString("{")
+	out.WriteString(strings.Join(pairs, ", "))
+	out.WriteString("}")
+
+	return out.String()
+}
+
+func (h HashLiteral) expressionNode() {
+}
@@ -16,6 +16,10 @@ func (i IfExpression) TokenLiteral() string {
 	return i.Token.Literal
 }
 
+func (i IfExpression) Pos() token.Position {
+	return i.Token.Pos
+}
+
 func (i IfExpression) String() string {
 	var out bytes.Buffer
 
@@ -0,0 +1,37 @@
+package ast
+
+import (
+	"bytes"
+	"github.com/jacksonopp/monkey/token"
+)
+
+// IndexExpression
+// ex: `myArray[1 + 1]`
+type IndexExpression struct {
+	Token token.Token // token.LBRACKET
+	Left  Expression  // the expression being indexed
+	Index Expression
+}
+
+func (i IndexExpression) TokenLiteral() string {
+	return i.Token.Literal
+}
+
+func (i IndexExpression) Pos() token.Position {
+	return i.Token.Pos
+}
+
+func (i IndexExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(i.Left.String())
+	out.WriteString("[")
+	out.WriteString(i.Index.String())
+	out.WriteString("])")
+
+	return out.String()
+}
+
+func (i IndexExpression) expressionNode() {
+}
@@ -0,0 +1,29 @@
+package ast
+
+import (
+	"github.com/jacksonopp/monkey/token"
+)
+
+// NamedArgument is an argument passed to a parameter by its name
+// ex: `b: 2` in `f(a: 1, b: 2)`
+type NamedArgument struct {
+	Token token.Token // token.IDENT, the name of the parameter
+	Name  *Identifier
+	Value Expression
+}
+
+func (n NamedArgument) TokenLiteral() string {
+	return n.Token.Literal
+}
+
+func (n NamedArgument) Pos() token.Position {
+	return n.Token.Pos
+}
+
+func (n NamedArgument) String() string {
+	return n.Name.String() + ": " + n.Value.String()
+}
+
+func (n NamedArgument) expressionNode() {
+
+}
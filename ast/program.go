@@ -1,6 +1,9 @@
 package ast
 
-import "bytes"
+import (
+	"bytes"
+	"github.com/jacksonopp/monkey/token"
+)
 
 // Program is the root node
 type Program struct {
@@ -24,3 +27,10 @@ func (p *Program) TokenLiteral() string {
 		return ""
 	}
 }
+
+func (p *Program) Pos() token.Position {
+	if len(p.Statements) > 0 {
+		return p.Statements[0].Pos()
+	}
+	return token.Position{}
+}
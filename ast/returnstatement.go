@@ -27,3 +27,7 @@ func (rs *ReturnStatement) statementNode() {}
 func (rs *ReturnStatement) TokenLiteral() string {
 	return rs.Token.Literal
 }
+
+func (rs *ReturnStatement) Pos() token.Position {
+	return rs.Token.Pos
+}
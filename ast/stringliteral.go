@@ -0,0 +1,25 @@
+package ast
+
+import "github.com/jacksonopp/monkey/token"
+
+// StringLiteral
+// ex: `"hello world"`
+type StringLiteral struct {
+	Token token.Token // token.STRING
+	Value string      // the string with its escape sequences already decoded
+}
+
+func (s StringLiteral) TokenLiteral() string {
+	return s.Token.Literal
+}
+
+func (s StringLiteral) Pos() token.Position {
+	return s.Token.Pos
+}
+
+func (s StringLiteral) String() string {
+	return s.Token.Literal
+}
+
+func (s StringLiteral) expressionNode() {
+}
@@ -0,0 +1,94 @@
+package code
+
+import "testing"
+
+func TestMake(t *testing.T) {
+	tests := []struct {
+		name     string
+		op       Opcode
+		operands []int
+		expected []byte
+	}{
+		{"two byte operand", OpConstant, []int{65534}, []byte{byte(OpConstant), 255, 254}},
+		{"no operands", OpAdd, []int{}, []byte{byte(OpAdd)}},
+		{"one byte operand", OpGetLocal, []int{255}, []byte{byte(OpGetLocal), 255}},
+		{"mixed operands", OpClosure, []int{65534, 255}, []byte{byte(OpClosure), 255, 254, 255}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instruction := Make(tt.op, tt.operands...)
+
+			if len(instruction) != len(tt.expected) {
+				t.Fatalf("instruction has wrong length. want=%d, got=%d", len(tt.expected), len(instruction))
+			}
+
+			for i, b := range tt.expected {
+				if instruction[i] != b {
+					t.Errorf("wrong byte at pos %d. want=%d, got=%d", i, b, instruction[i])
+				}
+			}
+		})
+	}
+}
+
+func TestInstructionsString(t *testing.T) {
+	instructions := []Instructions{
+		Make(OpAdd),
+		Make(OpGetLocal, 1),
+		Make(OpConstant, 2),
+		Make(OpConstant, 65535),
+		Make(OpClosure, 65535, 255),
+	}
+
+	expected := `0000 OpAdd
+0001 OpGetLocal 1
+0003 OpConstant 2
+0006 OpConstant 65535
+0009 OpClosure 65535 255
+`
+
+	concatted := Instructions{}
+	for _, ins := range instructions {
+		concatted = append(concatted, ins...)
+	}
+
+	if concatted.String() != expected {
+		t.Errorf("instructions wrongly formatted.\nwant=%q\ngot=%q", expected, concatted.String())
+	}
+}
+
+func TestReadOperands(t *testing.T) {
+	tests := []struct {
+		name      string
+		op        Opcode
+		operands  []int
+		bytesRead int
+	}{
+		{"two byte operand", OpConstant, []int{65535}, 2},
+		{"one byte operand", OpGetLocal, []int{255}, 1},
+		{"mixed operands", OpClosure, []int{65535, 255}, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instruction := Make(tt.op, tt.operands...)
+
+			def, err := Lookup(byte(tt.op))
+			if err != nil {
+				t.Fatalf("definition not found: %q\n", err)
+			}
+
+			operandsRead, n := ReadOperands(def, instruction[1:])
+			if n != tt.bytesRead {
+				t.Fatalf("n wrong. want=%d, got=%d", tt.bytesRead, n)
+			}
+
+			for i, want := range tt.operands {
+				if operandsRead[i] != want {
+					t.Errorf("operand wrong. want=%d, got=%d", want, operandsRead[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,408 @@
+package compiler
+
+import (
+	"github.com/jacksonopp/monkey/code"
+	"github.com/jacksonopp/monkey/lexer"
+	"github.com/jacksonopp/monkey/object"
+	"github.com/jacksonopp/monkey/parser"
+	"testing"
+)
+
+type compilerTestCase struct {
+	name                 string
+	input                string
+	expectedConstants    []interface{}
+	expectedInstructions []code.Instructions
+}
+
+func TestExpressions(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			"integer arithmetic",
+			"1 + 2",
+			[]interface{}{1, 2},
+			[]code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpAdd),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			"less than keeps operand order",
+			"1 < 2",
+			[]interface{}{1, 2},
+			[]code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpLessThan),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			"greater or equal",
+			"1 >= 2",
+			[]interface{}{1, 2},
+			[]code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpGreaterEqual),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			"modulo",
+			"1 % 2",
+			[]interface{}{1, 2},
+			[]code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpMod),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			"prefix",
+			"!true",
+			[]interface{}{},
+			[]code.Instructions{
+				code.Make(code.OpTrue),
+				code.Make(code.OpBang),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			"string",
+			`"monkey"`,
+			[]interface{}{"monkey"},
+			[]code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			"array",
+			"[1, 2]",
+			[]interface{}{1, 2},
+			[]code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpArray, 2),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			"hash index",
+			"{1: 2}[1]",
+			[]interface{}{1, 2, 1},
+			[]code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpHash, 2),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpIndex),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+func TestConditionals(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			"if without else",
+			"if (true) { 10 }; 3333;",
+			[]interface{}{10, 3333},
+			[]code.Instructions{
+				// 0000
+				code.Make(code.OpTrue),
+				// 0001
+				code.Make(code.OpJumpNotTruthy, 10),
+				// 0004
+				code.Make(code.OpConstant, 0),
+				// 0007
+				code.Make(code.OpJump, 11),
+				// 0010
+				code.Make(code.OpNull),
+				// 0011
+				code.Make(code.OpPop),
+				// 0012
+				code.Make(code.OpConstant, 1),
+				// 0015
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			"logical and",
+			"true && false",
+			[]interface{}{},
+			[]code.Instructions{
+				// 0000
+				code.Make(code.OpTrue),
+				// 0001
+				code.Make(code.OpDup),
+				// 0002
+				code.Make(code.OpJumpNotTruthy, 7),
+				// 0005
+				code.Make(code.OpPop),
+				// 0006
+				code.Make(code.OpFalse),
+				// 0007
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			"logical or",
+			"true || false",
+			[]interface{}{},
+			[]code.Instructions{
+				// 0000
+				code.Make(code.OpTrue),
+				// 0001
+				code.Make(code.OpDup),
+				// 0002
+				code.Make(code.OpJumpNotTruthy, 8),
+				// 0005
+				code.Make(code.OpJump, 10),
+				// 0008
+				code.Make(code.OpPop),
+				// 0009
+				code.Make(code.OpFalse),
+				// 0010
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			"if with else",
+			"if (true) { 10 } else { 20 }",
+			[]interface{}{10, 20},
+			[]code.Instructions{
+				// 0000
+				code.Make(code.OpTrue),
+				// 0001
+				code.Make(code.OpJumpNotTruthy, 10),
+				// 0004
+				code.Make(code.OpConstant, 0),
+				// 0007
+				code.Make(code.OpJump, 13),
+				// 0010
+				code.Make(code.OpConstant, 1),
+				// 0013
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+func TestFunctions(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			"global let and call",
+			"let one = fn() { 1 }; one();",
+			[]interface{}{
+				1,
+				[]code.Instructions{
+					code.Make(code.OpConstant, 0),
+					code.Make(code.OpReturnValue),
+				},
+			},
+			[]code.Instructions{
+				code.Make(code.OpClosure, 1, 0),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpCall, 0),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			"closure over a parameter",
+			"fn(a) { fn(b) { a + b } }",
+			[]interface{}{
+				[]code.Instructions{
+					code.Make(code.OpGetFree, 0),
+					code.Make(code.OpGetLocal, 0),
+					code.Make(code.OpAdd),
+					code.Make(code.OpReturnValue),
+				},
+				[]code.Instructions{
+					code.Make(code.OpGetLocal, 0),
+					code.Make(code.OpClosure, 0, 1),
+					code.Make(code.OpReturnValue),
+				},
+			},
+			[]code.Instructions{
+				code.Make(code.OpClosure, 1, 0),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			"recursive function",
+			"let f = fn(x) { f(x) };",
+			[]interface{}{
+				[]code.Instructions{
+					code.Make(code.OpCurrentClosure),
+					code.Make(code.OpGetLocal, 0),
+					code.Make(code.OpCall, 1),
+					code.Make(code.OpReturnValue),
+				},
+			},
+			[]code.Instructions{
+				code.Make(code.OpClosure, 0, 0),
+				code.Make(code.OpSetGlobal, 0),
+			},
+		},
+		{
+			"empty body returns null",
+			"fn() { }",
+			[]interface{}{
+				[]code.Instructions{
+					code.Make(code.OpReturn),
+				},
+			},
+			[]code.Instructions{
+				code.Make(code.OpClosure, 0, 0),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+func TestBuiltins(t *testing.T) {
+	program := parser.New(lexer.New("len([]); len([]); fn() { len }")).ParseProgram()
+
+	compiler := New()
+	if err := compiler.Compile(program); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	constants := compiler.Bytecode().Constants
+	if len(constants) != 2 { // len and the function
+		t.Fatalf("wrong number of constants. got=%d", len(constants))
+	}
+
+	builtin, ok := constants[0].(*object.Builtin)
+	if !ok || builtin.Name != "len" {
+		t.Errorf("constant is not the len builtin. got=%T (%+v)", constants[0], constants[0])
+	}
+
+	t.Run("across compilations", func(t *testing.T) {
+		next := NewWithState(compiler.symbolTable, constants)
+		if err := next.Compile(parser.New(lexer.New("len")).ParseProgram()); err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+
+		if len(next.Bytecode().Constants) != 2 {
+			t.Errorf("wrong number of constants. got=%d", len(next.Bytecode().Constants))
+		}
+	})
+}
+
+func TestUndefinedIdentifier(t *testing.T) {
+	program := parser.New(lexer.New("foobar")).ParseProgram()
+
+	compiler := New()
+	err := compiler.Compile(program)
+	if err == nil {
+		t.Fatalf("expected compiler error, got none")
+	}
+	if err.Error() != "1:1: identifier not found: foobar" {
+		t.Errorf("wrong error. got=%q", err.Error())
+	}
+}
+
+func runCompilerTests(t *testing.T, tests []compilerTestCase) {
+	t.Helper()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			program := parser.New(lexer.New(tt.input)).ParseProgram()
+
+			compiler := New()
+			err := compiler.Compile(program)
+			if err != nil {
+				t.Fatalf("compiler error: %s", err)
+			}
+
+			bytecode := compiler.Bytecode()
+
+			testInstructions(t, tt.expectedInstructions, bytecode.Instructions)
+			testConstants(t, tt.expectedConstants, bytecode.Constants)
+		})
+	}
+}
+
+func testInstructions(t *testing.T, expected []code.Instructions, actual code.Instructions) {
+	t.Helper()
+
+	concatted := code.Instructions{}
+	for _, ins := range expected {
+		concatted = append(concatted, ins...)
+	}
+
+	if actual.String() != concatted.String() {
+		t.Fatalf("wrong instructions.\nwant=%q\ngot =%q", concatted, actual)
+	}
+}
+
+func testConstants(t *testing.T, expected []interface{}, actual []object.Object) {
+	t.Helper()
+
+	if len(expected) != len(actual) {
+		t.Fatalf("wrong number of constants. want=%d, got=%d", len(expected), len(actual))
+	}
+
+	for i, constant := range expected {
+		switch constant := constant.(type) {
+		case int:
+			integer, ok := actual[i].(*object.Integer)
+			if !ok || integer.Value != int64(constant) {
+				t.Errorf("constant %d is not Integer %d. got=%T (%+v)", i, constant, actual[i], actual[i])
+			}
+		case string:
+			str, ok := actual[i].(*object.String)
+			if !ok || str.Value != constant {
+				t.Errorf("constant %d is not String %q. got=%T (%+v)", i, constant, actual[i], actual[i])
+			}
+		case []code.Instructions:
+			fn, ok := actual[i].(*object.CompiledFunction)
+			if !ok {
+				t.Fatalf("constant %d is not CompiledFunction. got=%T", i, actual[i])
+			}
+			testInstructions(t, constant, fn.Instructions)
+		}
+	}
+}
+
+func TestUnsupportedNode(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"while loop", "let x = 1;\nwhile (x) { break; }", "2:1: *ast.WhileStatement is not supported by the vm"},
+		{"default parameter", "fn(a, b = 2) { a }", "1:7: default parameter values are not supported by the vm"},
+		{"rest parameter", "fn(a, ...rest) { a }", "1:10: rest parameters are not supported by the vm"},
+		{"named argument", "let f = fn(a) { a };\nf(a: 1)", "2:3: *ast.NamedArgument is not supported by the vm"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			program := parser.New(lexer.New(tt.input)).ParseProgram()
+
+			compiler := New()
+			err := compiler.Compile(program)
+			if err == nil {
+				t.Fatalf("expected compiler error, got none")
+			}
+			if err.Error() != tt.expected {
+				t.Errorf("wrong error. want=%q, got=%q", tt.expected, err.Error())
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package compiler
+
+import "testing"
+
+func TestDefine(t *testing.T) {
+	global := NewSymbolTable()
+
+	a := global.Define("a")
+	if a != (Symbol{Name: "a", Scope: GlobalScope, Index: 0}) {
+		t.Errorf("a wrong. got=%+v", a)
+	}
+
+	b := global.Define("b")
+	if b != (Symbol{Name: "b", Scope: GlobalScope, Index: 1}) {
+		t.Errorf("b wrong. got=%+v", b)
+	}
+
+	again := global.Define("a")
+	if again != a {
+		t.Errorf("redefining a did not reuse its slot. got=%+v", again)
+	}
+
+	local := NewEnclosedSymbolTable(global)
+	c := local.Define("a")
+	if c != (Symbol{Name: "a", Scope: LocalScope, Index: 0}) {
+		t.Errorf("local a wrong. got=%+v", c)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	global := NewSymbolTable()
+	global.Define("a")
+
+	firstLocal := NewEnclosedSymbolTable(global)
+	firstLocal.Define("b")
+
+	secondLocal := NewEnclosedSymbolTable(firstLocal)
+	secondLocal.Define("c")
+
+	tests := []struct {
+		name     string
+		table    *SymbolTable
+		expected []Symbol
+	}{
+		{
+			"first local",
+			firstLocal,
+			[]Symbol{
+				{Name: "a", Scope: GlobalScope, Index: 0},
+				{Name: "b", Scope: LocalScope, Index: 0},
+			},
+		},
+		{
+			"second local",
+			secondLocal,
+			[]Symbol{
+				{Name: "a", Scope: GlobalScope, Index: 0},
+				{Name: "b", Scope: FreeScope, Index: 0},
+				{Name: "c", Scope: LocalScope, Index: 0},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, sym := range tt.expected {
+				result, ok := tt.table.Resolve(sym.Name)
+				if !ok {
+					t.Errorf("name %s not resolvable", sym.Name)
+					continue
+				}
+				if result != sym {
+					t.Errorf("expected %s to resolve to %+v, got=%+v", sym.Name, sym, result)
+				}
+			}
+		})
+	}
+
+	if len(secondLocal.FreeSymbols) != 1 || secondLocal.FreeSymbols[0].Name != "b" {
+		t.Errorf("wrong free symbols. got=%+v", secondLocal.FreeSymbols)
+	}
+
+	if _, ok := secondLocal.Resolve("d"); ok {
+		t.Errorf("name d resolved, but was never defined")
+	}
+}
+
+func TestDefineFunctionName(t *testing.T) {
+	global := NewSymbolTable()
+	global.DefineFunctionName("f")
+
+	result, ok := global.Resolve("f")
+	if !ok {
+		t.Fatalf("function name f not resolvable")
+	}
+	if result != (Symbol{Name: "f", Scope: FunctionScope, Index: 0}) {
+		t.Errorf("f wrong. got=%+v", result)
+	}
+
+	shadowed := global.Define("f")
+	if shadowed.Scope != GlobalScope {
+		t.Errorf("defining f did not shadow the function name. got=%+v", shadowed)
+	}
+}
@@ -0,0 +1,212 @@
+package enginetest
+
+// Group is a set of related cases.
+type Group struct {
+	Name  string
+	Cases []Case
+}
+
+// Cases are the programs both engines are tested against.
+var Cases = []Group{
+	{"integer expressions", []Case{
+		{"literal", "5", 5},
+		{"another literal", "10", 10},
+		{"negative", "-5", -5},
+		{"addition", "5 + 5", 10},
+		{"multiplication", "5 * 5", 25},
+		{"precedence", "5 + 2 * 2", 9},
+		{"division and subtraction", "50 / 5 - 3", 7},
+		{"grouping", "2 * (3+4)", 14},
+		{"negative addition", "-30 + 10", -20},
+		{"modulo", "7 % 3 * 2", 2},
+		{"negative modulo", "-7 % 3", -1},
+	}},
+	{"boolean expressions", []Case{
+		{"true", "true", true},
+		{"false", "false", false},
+		{"less than", "1 < 2", true},
+		{"greater than", "1 > 2", false},
+		{"equal", "1 == 1", true},
+		{"not not equal", "1 != 1", false},
+		{"not equal", "1 != 2", true},
+		{"not equal integers", "1 == 2", false},
+		{"true equals true", "true == true", true},
+		{"false equals false", "false == false", true},
+		{"true equals false", "true == false", false},
+		{"true not equal false", "true != false", true},
+		{"false not equal true", "false != true", true},
+		{"comparison equals true", "(1 < 2) == true", true},
+		{"false comparison equals true", "(1 > 2) == true", false},
+		{"comparison equals false", "(1 < 2) == false", false},
+		{"false comparison equals false", "(1 > 2) == false", true},
+		{"less or equal", "1 <= 1", true},
+		{"not less or equal", "2 <= 1", false},
+		{"greater or equal", "1 >= 1", true},
+		{"not greater or equal", "1 >= 2", false},
+		{"and", "1 < 2 && 2 < 3", true},
+		{"or", "1 > 2 || 2 > 3", false},
+		{"and binds tighter than or", "true || false && false", true},
+	}},
+	{"logical operators", []Case{
+		{"and returns the right operand", "1 && 2", 2},
+		{"and returns a falsy left operand", "0 && false", false},
+		{"and short-circuits", "false && 2", false},
+		{"or returns the right operand", "false || 2", 2},
+		{"or returns a truthy left operand", "1 || 2", 1},
+		{"or with null", `if (false) { 1 } || "default"`, "default"},
+		{"and with null", "if (false) { 1 } && 2", nil},
+		{"or skips an error", "let called = fn() { 1 + true }; 1 || called()", 1},
+		{"error in right operand of and", "true && 1 + true", Error("type mismatch: INTEGER + BOOLEAN")},
+	}},
+	{"string expressions", []Case{
+		{"literal", `"Hello World!"`, "Hello World!"},
+		{"concatenation", `"Hello" + " " + "World!"`, "Hello World!"},
+		{"escapes", `"tab\tnew\nline"`, "tab\tnew\nline"},
+		{"bound", `let greeting = "hi"; greeting + "!"`, "hi!"},
+		{"equal", `"monkey" == "monkey"`, true},
+		{"not equal", `"monkey" != "monkey"`, false},
+		{"different", `"monkey" == "ape"`, false},
+	}},
+	{"bang operator", []Case{
+		{"not true", "!true", false},
+		{"not false", "!false", true},
+		{"not 5", "!5", false},
+		{"not not true", "!!true", true},
+		{"not not false", "!!false", false},
+		{"not not 5", "!!5", true},
+	}},
+	{"if else expressions", []Case{
+		{"true", "if (true) { 10 }", 10},
+		{"false", "if (false) { 10 }", nil},
+		{"truthy", "if (1) { 10 }", 10},
+		{"comparison true", "if (1 < 10) { 10 }", 10},
+		{"comparison false", "if (1 > 10) { 10 }", nil},
+		{"comparison else is true", "if (1 > 10) { 10 } else { 20 }", 20},
+		{"comparison else is false", "if (1 < 10) { 10 } else { 20 }", 10},
+	}},
+	{"functions", []Case{
+		{"identity", "let identity = fn(x) { x; }; identity(5);", 5},
+		{"identity with return", "let identity = fn(x) { return x; }; identity(5);", 5},
+		{"double", "let double = fn(x) { x * 2; }; double(5);", 10},
+		{"add", "let add = fn(x, y) { x + y; }; add(5, 5);", 10},
+		{"add with calling add", "let add = fn(x, y) { x + y; }; add(5, add(5, 5));", 10 + 5},
+		{"anonymous iife", "fn(x){ x; }(5);", 5},
+		{"closure", "let newAdder = fn(x) { fn(y) { x + y }; }; let addTwo = newAdder(2); addTwo(2);", 4},
+	}},
+	{"arrays", []Case{
+		{"literal", "[1, 2 * 2, 3 + 3]", []int{1, 4, 6}},
+		{"first", "[1, 2, 3][0]", 1},
+		{"last", "[1, 2, 3][2]", 3},
+		{"computed index", "let i = 0; [1][i + 0];", 1},
+		{"bound array", "let myArray = [1, 2, 3]; myArray[2];", 3},
+		{"sum of elements", "let myArray = [1, 2, 3]; myArray[0] + myArray[1] + myArray[2];", 6},
+		{"negative index", "[1, 2, 3][-1]", 3},
+		{"negative index from start", "[1, 2, 3][-3]", 1},
+		{"out of range", "[1, 2, 3][3]", nil},
+		{"negative out of range", "[1, 2, 3][-4]", nil},
+		{"function element", "[fn(x) { x * 2 }][0](4)", 8},
+	}},
+	{"hashes", []Case{
+		{
+			"literal",
+			`let two = "two"; {"one": 10 - 9, two: 1 + 1, "thr" + "ee": 6 / 2, 4: 4, true: 5, false: 6}`,
+			Inspected("{one: 1, two: 2, three: 3, 4: 4, true: 5, false: 6}"),
+		},
+		{"string key", `{"foo": 5}["foo"]`, 5},
+		{"missing key", `{"foo": 5}["bar"]`, nil},
+		{"bound key", `let key = "foo"; {"foo": 5}[key]`, 5},
+		{"empty hash", `{}["foo"]`, nil},
+		{"integer key", `{5: 5}[5]`, 5},
+		{"true key", `{true: 5}[true]`, 5},
+		{"false key", `{false: 5}[false]`, 5},
+		{"later key wins", `{"a": 1, "a": 2}["a"]`, 2},
+		{"inspect keeps insertion order", `{"b": 1, "a": 2, 3: [true]}`, Inspected("{b: 1, a: 2, 3: [true]}")},
+	}},
+	{"statements", []Case{
+		{"basic return", "return 10;", 10},
+		{"not post return", "return 10; 9;", 10},
+		{"not post return, with calculation", "return 2 * 5; 9;", 10},
+		{"not pre return or post return", "9; return 2 * 5; 9;", 10},
+		{"return inside a nested statement", "if (10 > 1) { if (10 > 1) { return 10; } return 1; }", 10},
+		{"simple let", "let a = 10; a;", 10},
+		{"let with calculation", "let a = 5 * 2; a;", 10},
+		{"multiple lets", "let a = 10; let b = a; b;", 10},
+		{"multiple lets with calculations", "let a = 5; let b = 3; let c = a * b - 5; c;", 10},
+		{"function ending in a statement", "let g = fn() { let x = 1 }; g()", nil},
+		{"empty function", "fn() { }()", nil},
+		{"if ending in a statement", "let y = if (true) { let x = 1 }; y", nil},
+		{"result of a function ending in a statement", "let g = fn() { let x = 1 }; g() + 1", Error("type mismatch: NULL + INTEGER")},
+		{"as a hash key", "let g = fn() { let x = 1 }; {g(): 1}", Error("unusable as hash key: NULL")},
+	}},
+	{"builtin functions", []Case{
+		{"len empty string", `len("")`, 0},
+		{"len string", `len("four")`, 4},
+		{"len unicode string", `len("\u{1F412}!")`, 2},
+		{"len array", `len([1, 2, 3])`, 3},
+		{"len unsupported", `len(1)`, Error("argument to `len` not supported, got INTEGER")},
+		{"len too many arguments", `len("one", "two")`, Error("wrong number of arguments to `len`: want=1, got=2")},
+		{"first", `first([1, 2, 3])`, 1},
+		{"first empty", `first([])`, nil},
+		{"first not array", `first(1)`, Error("argument to `first` must be ARRAY, got INTEGER")},
+		{"last", `last([1, 2, 3])`, 3},
+		{"last empty", `last([])`, nil},
+		{"last not array", `last(1)`, Error("argument to `last` must be ARRAY, got INTEGER")},
+		{"rest", `rest([1, 2, 3])`, []int{2, 3}},
+		{"rest empty", `rest([])`, nil},
+		{"push", `push([], 1)`, []int{1}},
+		{"push not array", `push(1, 1)`, Error("argument to `push` must be ARRAY, got INTEGER")},
+		{"push leaves original", `let a = [1]; let b = push(a, 2); len(a)`, 1},
+		{"type of integer", `type(1) == "INTEGER"`, true},
+		{"type of builtin", `type(len) == "BUILTIN"`, true},
+		{"type without arguments", `type()`, Error("wrong number of arguments to `type`: want=1, got=0")},
+		{"int of float", `int(3.9)`, 3},
+		{"int of negative float", `int(-3.9)`, -3},
+		{"int of string", `int(" 42 ")`, 42},
+		{"int of integer", `int(7)`, 7},
+		{"int of big float", `type(int(1e20)) == "BIGINT"`, true},
+		{"int of infinity", `int(1.0 / 0)`, Error("cannot convert +Inf to an integer")},
+		{"int of bad string", `int("4x")`, Error(`cannot convert "4x" to an integer`)},
+		{"int unsupported", `int(true)`, Error("argument to `int` not supported, got BOOLEAN")},
+		{"float of integer", `float(2)`, 2.0},
+		{"float of string", `float("1e-3")`, 0.001},
+		{"float of float", `float(1.5)`, 1.5},
+		{"float of bad string", `float("one")`, Error(`cannot convert "one" to a float`)},
+		{"float unsupported", `float([])`, Error("argument to `float` not supported, got ARRAY")},
+		{"len of a function ending in a statement", "let g = fn() { let x = 1 }; len(g())", Error("argument to `len` not supported, got NULL")},
+		{"first of a function ending in a statement", "let g = fn() { let x = 1 }; first(g())", Error("argument to `first` must be ARRAY, got NULL")},
+		{"push to a function ending in a statement", "let g = fn() { let x = 1 }; push(g(), 1)", Error("argument to `push` must be ARRAY, got NULL")},
+		{"type of a function ending in a statement", `let g = fn() { let x = 1 }; type(g()) == "NULL"`, true},
+		{"int of a function ending in a statement", "let g = fn() { let x = 1 }; int(g())", Error("argument to `int` not supported, got NULL")},
+	}},
+	{"errors", []Case{
+		{"type mismatch", "5 + true", Error("type mismatch: INTEGER + BOOLEAN")},
+		{"adding int and bool", "5 + true; 8;", Error("type mismatch: INTEGER + BOOLEAN")},
+		{"negative true", "-true", Error("unknown operator: -BOOLEAN")},
+		{"adding bools", "true + true", Error("unknown operator: BOOLEAN + BOOLEAN")},
+		{"adding bools nested", "if (20 > 1) { true + false }", Error("unknown operator: BOOLEAN + BOOLEAN")},
+		{
+			"adding bools deep nested",
+			"if (10 > 1) { if (10 > 1) { return true + false; } return 1; }",
+			Error("unknown operator: BOOLEAN + BOOLEAN"),
+		},
+		{"subtracting strings", `"Hello" - "World"`, Error("unknown operator: STRING - STRING")},
+		{"adding string and int", `"Hello" + 1`, Error("type mismatch: STRING + INTEGER")},
+		{"indexing an integer", "1[0]", Error("index operator not supported: INTEGER[INTEGER]")},
+		{"division by zero", "1 / 0", Error("division by zero")},
+		{"modulo by zero", "5 % (2 - 2)", Error("modulo by zero")},
+		{"function as hash key", `{"name": "Monkey"}[fn(x) { x }];`, Error("unusable as hash key: FUNCTION")},
+		{"function as hash literal key", `{fn(x) { x }: "Monkey"}`, Error("unusable as hash key: FUNCTION")},
+		{"too few arguments", "fn(a, b) { a }(1)", Error("wrong number of arguments: want=2, got=1")},
+		{"too many arguments", "fn() { 1 }(1, 2)", Error("wrong number of arguments: want=0, got=2")},
+		{
+			"wrong number of arguments to a named function",
+			"let add = fn(a, b) { a + b }; add(1);",
+			Error("wrong number of arguments to `add`: want=2, got=1"),
+		},
+		{
+			"functions are named where they are defined",
+			"let add = fn(a, b) { a + b }; let plus = add; plus(1, 2, 3);",
+			Error("wrong number of arguments to `add`: want=2, got=3"),
+		},
+	}},
+}
@@ -0,0 +1,106 @@
+// Package enginetest holds the programs that the evaluator and the virtual machine are both
+// tested against, so that the two engines give the same results.
+package enginetest
+
+import (
+	"github.com/jacksonopp/monkey/object"
+	"testing"
+)
+
+// Case is a program and what it evaluates to.
+type Case struct {
+	Name  string
+	Input string
+
+	// Expected is the result: an int, float64, bool or string for an object of that type,
+	// nil for NULL, []int for an array of integers, Error for an error or Inspected for
+	// anything else.
+	Expected interface{}
+}
+
+// Error is the message of an expected *object.Error, without its position.
+type Error string
+
+// Inspected is the expected Inspect output of a result.
+type Inspected string
+
+// Run runs each of Cases as a subtest of t, executing its program with run.
+func Run(t *testing.T, run func(t *testing.T, input string) object.Object) {
+	for _, group := range Cases {
+		t.Run(group.Name, func(t *testing.T) {
+			for _, tt := range group.Cases {
+				t.Run(tt.Name, func(t *testing.T) {
+					testObject(t, tt.Expected, run(t, tt.Input))
+				})
+			}
+		})
+	}
+}
+
+func testObject(t *testing.T, expected interface{}, actual object.Object) {
+	t.Helper()
+
+	switch expected := expected.(type) {
+	case int:
+		integer, ok := actual.(*object.Integer)
+		if !ok {
+			t.Fatalf("object is not Integer. got=%T (%+v)", actual, actual)
+		}
+		if integer.Value != int64(expected) {
+			t.Errorf("object has wrong value. want=%d, got=%d", expected, integer.Value)
+		}
+	case float64:
+		float, ok := actual.(*object.Float)
+		if !ok {
+			t.Fatalf("object is not Float. got=%T (%+v)", actual, actual)
+		}
+		if float.Value != expected {
+			t.Errorf("object has wrong value. want=%g, got=%g", expected, float.Value)
+		}
+	case bool:
+		boolean, ok := actual.(*object.Boolean)
+		if !ok {
+			t.Fatalf("object is not Boolean. got=%T (%+v)", actual, actual)
+		}
+		if boolean.Value != expected {
+			t.Errorf("object has wrong value. want=%t, got=%t", expected, boolean.Value)
+		}
+	case string:
+		str, ok := actual.(*object.String)
+		if !ok {
+			t.Fatalf("object is not String. got=%T (%+v)", actual, actual)
+		}
+		if str.Value != expected {
+			t.Errorf("object has wrong value. want=%q, got=%q", expected, str.Value)
+		}
+	case nil:
+		if actual != object.NULL {
+			t.Errorf("object is not NULL. got=%T (%+v)", actual, actual)
+		}
+	case []int:
+		array, ok := actual.(*object.Array)
+		if !ok {
+			t.Fatalf("object is not Array. got=%T (%+v)", actual, actual)
+		}
+		if len(array.Elements) != len(expected) {
+			t.Fatalf("wrong number of elements. want=%d, got=%d", len(expected), len(array.Elements))
+		}
+		for i, element := range expected {
+			testObject(t, element, array.Elements[i])
+		}
+	case Error:
+		errObj, ok := actual.(*object.Error)
+		if !ok {
+			t.Fatalf("object is not Error. got=%T (%+v)", actual, actual)
+		}
+		if errObj.Message != string(expected) {
+			t.Errorf("wrong error message. want=%q, got=%q", expected, errObj.Message)
+		}
+	case Inspected:
+		if actual.Inspect() != string(expected) {
+			t.Errorf("wrong Inspect. want=%q, got=%q", expected, actual.Inspect())
+		}
+	default:
+		t.Fatalf("unsupported expected result %T", expected)
+	}
+}
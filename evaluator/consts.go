@@ -3,9 +3,9 @@ package evaluator
 import "github.com/jacksonopp/monkey/object"
 
 var (
-	NULL  = &object.Null{}
-	TRUE  = &object.Boolean{Value: true}
-	FALSE = &object.Boolean{Value: false}
+	NULL  = object.NULL
+	TRUE  = object.TRUE
+	FALSE = object.FALSE
 )
 
 func nativeBoolToBooleanObject(input bool) *object.Boolean {
@@ -1,80 +1,443 @@
 package evaluator
 
 import (
+	"context"
+	"errors"
 	"github.com/jacksonopp/monkey/ast"
 	"github.com/jacksonopp/monkey/object"
+	"github.com/jacksonopp/monkey/token"
+	"math"
+	"math/big"
+	"strings"
+	"unicode/utf8"
 )
 
+// DefaultMaxDepth is how deeply function calls can nest when an Evaluator does not set
+// MaxDepth.
+const DefaultMaxDepth = 10000
+
+// Evaluator is a tree-walking interpreter for Monkey programs. The zero value is ready
+// to use and behaves like the package level Eval. An Evaluator keeps track of the calls
+// in progress, so it must not be used by more than one goroutine at a time.
+type Evaluator struct {
+	// CheckedArithmetic makes integer arithmetic that overflows int64 an error instead
+	// of promoting the result to a BigInt.
+	CheckedArithmetic bool
+
+	// MaxDepth is how deeply function calls can nest before the call that goes too deep
+	// is an error, rather than exhausting the Go stack. Zero means DefaultMaxDepth.
+	MaxDepth int
+
+	// MaxSteps limits how many nodes can be evaluated, counting from the start of the
+	// last call to EvalContext. Zero means there is no limit.
+	MaxSteps int
+
+	// MaxMemory limits how many bytes of objects and environments the program can hold
+	// at once during a call to EvalContext. Zero means there is no limit.
+	MaxMemory int64
+
+	depth       int                   // how many calls to Monkey functions are in progress
+	steps       int                   // how many nodes have been evaluated
+	memory      int64                 // how many bytes the program holds, counting garbage since the last measurement
+	peak        int64                 // the highest memory has been
+	nextMeasure int64                 // how high memory can get before it is measured again
+	scopes      []*object.Environment // the environments the program is running in
+	pending     []object.Object       // values being computed that are not bound to a name yet
+	running     int                   // how many calls to EvalContext and CallContext are in progress
+	ctx         context.Context       // the context of the current call to EvalContext, if any
+}
+
+// ErrStepBudgetExhausted is the Cause of the error returned when evaluation takes more
+// than MaxSteps steps. Evaluation stopped by its context has the context's error as the
+// Cause instead, which is context.DeadlineExceeded or context.Canceled.
+var ErrStepBudgetExhausted = errors.New("step budget exhausted")
+
+// contextCheckInterval is how many steps are taken between checks of the context, which
+// are too slow to make on every step.
+const contextCheckInterval = 256
+
+// New creates an Evaluator with the default settings.
+func New() *Evaluator {
+	return &Evaluator{}
+}
+
+// Eval evaluates node in env with the default settings.
 func Eval(node ast.Node, env *object.Environment) object.Object {
+	return New().Eval(node, env)
+}
+
+// EvalContext evaluates node in env with the default settings, stopping when ctx is done.
+func EvalContext(ctx context.Context, node ast.Node, env *object.Environment) object.Object {
+	return New().EvalContext(ctx, node, env)
+}
+
+// EvalContext evaluates node in env like Eval, but stops with an error when ctx is done,
+// the evaluation takes more than MaxSteps steps or allocates more than MaxMemory bytes.
+// The Cause of the error tells the host which of them happened, and is nil for errors
+// raised by the program itself.
+func (e *Evaluator) EvalContext(ctx context.Context, node ast.Node, env *object.Environment) object.Object {
+	if err := e.start(ctx); err != nil {
+		return err
+	}
+	defer e.stop()
+
+	return e.Eval(node, env)
+}
+
+// CallContext calls fn, a Monkey function or a builtin, with args from the host, under
+// the same limits as EvalContext.
+func (e *Evaluator) CallContext(ctx context.Context, fn object.Object, args ...object.Object) object.Object {
+	if err := e.start(ctx); err != nil {
+		return err
+	}
+	defer e.stop()
+
+	return e.applyFunction(fn, args, nil, token.Position{})
+}
+
+// start begins an evaluation under ctx, counting steps and memory from zero. An evaluation
+// started while another is running, such as by a builtin calling back in to Monkey code,
+// is part of the one already running: it keeps counting its steps and memory and stops
+// when its context is done.
+func (e *Evaluator) start(ctx context.Context) *object.Error {
+	if err := ctx.Err(); err != nil {
+		return contextError(err)
+	}
+
+	e.running += 1
+	if e.running > 1 {
+		return nil
+	}
+
+	e.ctx = ctx
+	e.steps = 0
+	e.pending = e.pending[:0]
+	e.memory = 0
+	e.peak = 0
+	e.nextMeasure = measureMinimum
+	return nil
+}
+
+func (e *Evaluator) stop() {
+	e.running -= 1
+	if e.running == 0 {
+		e.ctx = nil
+	}
+}
+
+// Eval evaluates node in env. Errors produced while evaluating node are given its
+// position, unless an inner node has already claimed them.
+func (e *Evaluator) Eval(node ast.Node, env *object.Environment) object.Object {
+	if err := e.step(); err != nil {
+		err.Pos = node.Pos()
+		return err
+	}
+
+	result := e.eval(node, env)
+
+	if err, ok := result.(*object.Error); ok && !err.Pos.IsValid() {
+		err.Pos = node.Pos()
+	}
+
+	return result
+}
+
+// step counts the evaluation of a node, returning an error once the evaluation has to
+// stop.
+func (e *Evaluator) step() *object.Error {
+	e.steps += 1
+
+	if e.MaxSteps > 0 && e.steps > e.MaxSteps {
+		err := object.NewError("step budget of %d exhausted", e.MaxSteps)
+		err.Cause = ErrStepBudgetExhausted
+		return err
+	}
+
+	if e.ctx != nil && e.steps%contextCheckInterval == 0 {
+		if err := e.ctx.Err(); err != nil {
+			return contextError(err)
+		}
+	}
+
+	return nil
+}
+
+// contextError reports that evaluation was stopped because its context is done.
+func contextError(cause error) *object.Error {
+	message := "evaluation cancelled"
+	if errors.Is(cause, context.DeadlineExceeded) {
+		message = "evaluation timed out"
+	}
+
+	err := object.NewError("%s", message)
+	err.Cause = cause
+	return err
+}
+
+func (e *Evaluator) eval(node ast.Node, env *object.Environment) object.Object {
 	switch node := node.(type) {
 	//statements
 	case *ast.Program:
-		return evalProgram(node, env)
+		return e.evalProgram(node, env)
 	case *ast.ExpressionStatement:
-		return Eval(node.Expression, env)
+		return e.Eval(node.Expression, env)
 	case *ast.ReturnStatement:
-		val := Eval(node.ReturnValue, env)
+		val := e.Eval(node.ReturnValue, env)
 		if isError(val) {
 			return val
 		}
 		return &object.ReturnValue{Value: val}
 	case *ast.LetStatement:
-		val := Eval(node.Value, env)
-		if isError(val) {
+		val := e.Eval(node.Value, env)
+		if interrupts(val) {
 			return val
 		}
+		if env.IsConstant(node.Name.Value) {
+			return object.NewError("cannot redeclare constant %s", node.Name.Value)
+		}
+		nameFunction(node.Name, node.Value, val)
+		if err := e.chargeHolding(bindingSize, val); err != nil {
+			return err
+		}
 		env.Set(node.Name.Value, val)
+	case *ast.ConstStatement:
+		val := e.Eval(node.Value, env)
+		if interrupts(val) {
+			return val
+		}
+		if env.IsConstant(node.Name.Value) {
+			return object.NewError("cannot redeclare constant %s", node.Name.Value)
+		}
+		nameFunction(node.Name, node.Value, val)
+		if err := e.chargeHolding(bindingSize, val); err != nil {
+			return err
+		}
+		env.SetConstant(node.Name.Value, val)
 	case *ast.BlockStatement:
-		return evalBlockStatement(node, env)
+		return e.evalBlockStatement(node, env)
+	case *ast.WhileStatement:
+		return e.evalWhileStatement(node, env)
+	case *ast.ForStatement:
+		return e.evalForStatement(node, env)
+	case *ast.BreakStatement:
+		return object.BREAK
+	case *ast.ContinueStatement:
+		return object.CONTINUE
 
 	//	expressions
 	case *ast.FunctionLiteral:
 		params := node.Parameters
 		body := node.Body
-		return &object.Function{Parameters: params, Env: env, Body: body}
+		return e.allocate(&object.Function{Parameters: params, Defaults: node.Defaults, Rest: node.Rest, Env: env, Body: body})
 	case *ast.CallExpression:
-		function := Eval(node.Function, env)
+		defer e.release(len(e.pending))
+
+		function := e.Eval(node.Function, env)
 		if isError(function) {
 			return function
 		}
-		args := evalExpressions(node.Arguments, env)
-		if len(args) == 1 && isError(args[0]) {
-			return args[0]
+		e.hold(function)
+
+		// the arguments are held until the call returns
+		args, named, err := e.evalArguments(node.Arguments, env)
+		if err != nil {
+			return err
+		}
+		if node.Tail {
+			return &tailCall{function: function, args: args, named: named, callSite: node.Pos()}
 		}
-		return applyFunction(function, args)
+		return e.applyFunction(function, args, named, node.Pos())
 	case *ast.Identifier:
 		return evalIdentifier(node, env)
 	case *ast.IfExpression:
-		return evalIfExpression(node, env)
+		return e.evalIfExpression(node, env)
 	case *ast.IntegerLiteral:
-		return &object.Integer{Value: node.Value}
+		return e.allocate(&object.Integer{Value: node.Value})
+	case *ast.BigIntLiteral:
+		return e.allocate(&object.BigInt{Value: node.Value})
+	case *ast.FloatLiteral:
+		return e.allocate(&object.Float{Value: node.Value})
+	case *ast.StringLiteral:
+		return e.allocate(&object.String{Value: node.Value})
 	case *ast.Boolean:
 		return nativeBoolToBooleanObject(node.Value)
+	case *ast.ArrayLiteral:
+		defer e.release(len(e.pending))
+
+		elements := e.evalExpressions(node.Elements, env)
+		if len(elements) == 1 && isError(elements[0]) {
+			return elements[0]
+		}
+		return e.allocate(&object.Array{Elements: elements})
+	case *ast.HashLiteral:
+		return e.evalHashLiteral(node, env)
+	case *ast.IndexExpression:
+		defer e.release(len(e.pending))
+
+		left := e.Eval(node.Left, env)
+		if isError(left) {
+			return left
+		}
+		e.hold(left)
+
+		index := e.Eval(node.Index, env)
+		if isError(index) {
+			return index
+		}
+		return evalIndexExpression(left, index)
 	case *ast.PrefixExpression:
-		right := Eval(node.Right, env)
+		defer e.release(len(e.pending))
+
+		right := e.Eval(node.Right, env)
 		if isError(right) {
 			return right
 		}
-		return evalPrefixExpression(node.Operator, right)
+		e.hold(right)
+
+		return e.allocate(e.evalPrefixExpression(node.Operator, right))
+	case *ast.AssignExpression:
+		return e.evalAssignExpression(node, env)
 	case *ast.InfixExpression:
-		left := Eval(node.Left, env)
+		left := e.Eval(node.Left, env)
 		if isError(left) {
 			return left
 		}
-		right := Eval(node.Right, env)
+		if node.Operator == "&&" || node.Operator == "||" {
+			return e.evalLogicalExpression(node, left, env)
+		}
+
+		defer e.release(len(e.pending))
+		e.hold(left)
+
+		right := e.Eval(node.Right, env)
 		if isError(right) {
 			return right
 		}
-		return evalInfixExpression(node.Operator, left, right)
+		e.hold(right)
+
+		return e.allocate(e.evalInfixExpression(node.Operator, left, right))
 	}
 	return nil
 }
 
-func evalProgram(program *ast.Program, env *object.Environment) object.Object {
+// evalAssignExpression assigns to a variable or to an element of an array or hash. Compound
+// operators such as += combine the current value with the new one first.
+func (e *Evaluator) evalAssignExpression(node *ast.AssignExpression, env *object.Environment) object.Object {
+	defer e.release(len(e.pending))
+
+	switch target := node.Target.(type) {
+	case *ast.Identifier:
+		value := e.Eval(node.Value, env)
+		if interrupts(value) {
+			return value
+		}
+		e.hold(value)
+
+		if node.Operator != "=" {
+			current := evalIdentifier(target, env)
+			if isError(current) {
+				return current
+			}
+			value = e.evalCompoundValue(node.Operator, current, value)
+			if isError(value) {
+				return value
+			}
+		}
+
+		if err := env.Assign(target.Value, value); err != nil {
+			return err
+		}
+		return value
+	case *ast.IndexExpression:
+		left := e.Eval(target.Left, env)
+		if isError(left) {
+			return left
+		}
+		e.hold(left)
+
+		index := e.Eval(target.Index, env)
+		if isError(index) {
+			return index
+		}
+		e.hold(index)
+
+		value := e.Eval(node.Value, env)
+		if interrupts(value) {
+			return value
+		}
+		e.hold(value)
+
+		if node.Operator != "=" {
+			current := evalIndexExpression(left, index)
+			if isError(current) {
+				return current
+			}
+			value = e.evalCompoundValue(node.Operator, current, value)
+			if isError(value) {
+				return value
+			}
+		}
+
+		if hash, ok := left.(*object.Hash); ok {
+			if key, ok := index.(object.Hashable); ok {
+				if _, exists := hash.Get(key); !exists {
+					if err := e.charge(pairSize); err != nil {
+						return err
+					}
+				}
+			}
+		}
+
+		return evalIndexAssignment(left, index, value)
+	default:
+		return object.NewError("cannot assign to %s", node.Target.String())
+	}
+}
+
+// evalCompoundValue applies the infix operator of a compound assignment such as +=.
+func (e *Evaluator) evalCompoundValue(operator string, current, value object.Object) object.Object {
+	return e.allocate(e.evalInfixExpression(strings.TrimSuffix(operator, "="), current, value))
+}
+
+// evalIndexAssignment stores value in an array or hash. Array indexes must be in range,
+// counting back from the end when negative like they do when reading.
+func evalIndexAssignment(left, index, value object.Object) object.Object {
+	switch {
+	case left.Type() == object.ARRAY_OBJ && index.Type() == object.INTEGER_OBJ:
+		elements := left.(*object.Array).Elements
+		idx := index.(*object.Integer).Value
+		length := int64(len(elements))
+
+		if idx < 0 {
+			idx = idx + length
+		}
+		if idx < 0 || idx >= length {
+			return object.NewError("index out of range: %s", index.Inspect())
+		}
+
+		elements[idx] = value
+		return value
+	case left.Type() == object.HASH_OBJ:
+		key, ok := index.(object.Hashable)
+		if !ok {
+			return object.NewError("unusable as hash key: %s", index.Type())
+		}
+
+		left.(*object.Hash).Set(key, value)
+		return value
+	default:
+		return object.NewError("index assignment not supported: %s[%s]", left.Type(), index.Type())
+	}
+}
+
+func (e *Evaluator) evalProgram(program *ast.Program, env *object.Environment) object.Object {
+	e.enterScope(env)
+	defer e.exitScope()
+
 	var result object.Object
 
 	for _, stmt := range program.Statements {
-		result = Eval(stmt, env)
+		result = e.Eval(stmt, env)
 
 		switch result := result.(type) {
 		case *object.ReturnValue:
@@ -84,25 +447,278 @@ func evalProgram(program *ast.Program, env *object.Environment) object.Object {
 		}
 	}
 
-	return result
+	return orNull(result)
 }
 
-func applyFunction(fn object.Object, args []object.Object) object.Object {
-	function, ok := fn.(*object.Function)
-	if !ok {
+// applyFunction calls fn with the arguments of a call made at callSite.
+func (e *Evaluator) applyFunction(fn object.Object, args []object.Object, named []namedArgument, callSite token.Position) object.Object {
+	switch function := fn.(type) {
+	case *object.Function:
+		return e.callFunction(function, args, named, callSite)
+	case *object.Builtin:
+		if len(named) > 0 {
+			return object.NewError("builtin `%s` does not take named arguments", function.Name)
+		}
+		result := orNull(function.Fn(args...))
+		if isArgument(result, args) {
+			return result
+		}
+		return e.allocate(result)
+	default:
 		return object.NewError("not a function: %s", fn.Type())
 	}
-	extendedEnv := extendedFunctionEnv(function, args)
-	evaluated := Eval(function.Body, extendedEnv)
-	return unwrapReturnValue(evaluated)
 }
 
-func extendedFunctionEnv(fn *object.Function, args []object.Object) *object.Environment {
+// callFunction evaluates the body of fn with its parameters bound to the arguments.
+// Errors raised inside the body record the call in their stack. When the body ends in a
+// tail call, that call is made in a loop here instead of nesting, so it does not use any
+// more of the Go stack or count towards the recursion depth.
+func (e *Evaluator) callFunction(fn *object.Function, args []object.Object, named []namedArgument, callSite token.Position) object.Object {
+	if e.depth >= e.maxDepth() {
+		if fn.Name == "" {
+			return object.NewError("maximum recursion depth exceeded")
+		}
+		return object.NewError("maximum recursion depth exceeded in `%s`", fn.Name)
+	}
+
+	// default values can call functions too, so they count towards the depth
+	e.depth += 1
+	defer func() { e.depth -= 1 }()
+
+	extendedEnv, err := e.extendedFunctionEnv(fn, args, named)
+	if err != nil {
+		return err
+	}
+
+	e.enterScope(extendedEnv)
+	defer e.exitScope()
+
+	// a chain of tail calls is traced as the call that started it and the one that is
+	// running, rather than one frame for each call in the chain
+	first := object.Frame{Function: fn.Name, Pos: callSite, Args: len(args) + len(named)}
+	current := first
+	tailCalls := 0
+
+	for {
+		evaluated := unwrapReturnValue(e.Eval(fn.Body, extendedEnv))
+
+		call, ok := evaluated.(*tailCall)
+		if !ok {
+			return traceCall(orNull(evaluated), current, first, tailCalls)
+		}
+
+		// the arguments of a tail call are no longer held by the call expression
+		mark := len(e.pending)
+		e.holdArguments(call.args, call.named)
+
+		next, ok := call.function.(*object.Function)
+		if !ok {
+			result := atCallSite(e.applyFunction(call.function, call.args, call.named, call.callSite), call.callSite)
+			e.release(mark)
+			return traceCall(result, current, first, tailCalls)
+		}
+
+		extendedEnv, err = e.extendedFunctionEnv(next, call.args, call.named)
+		e.release(mark)
+		if err != nil {
+			return traceCall(atCallSite(err, call.callSite), current, first, tailCalls)
+		}
+
+		fn = next
+		e.scopes[len(e.scopes)-1] = extendedEnv
+		tailCalls += 1
+		// the first tail call is made by the function first called, which is traced, so
+		// only later ones have replaced calls the trace leaves out
+		current = object.Frame{Function: next.Name, Pos: call.callSite, Args: len(call.args) + len(call.named), TailCall: tailCalls > 1}
+	}
+}
+
+// traceCall records current, the call that was running when result was returned, in
+// the stack of result if it is an error. When current was reached through tailCalls tail
+// calls, first, the call that started them, is recorded after it.
+func traceCall(result object.Object, current, first object.Frame, tailCalls int) object.Object {
+	err, ok := result.(*object.Error)
+	if !ok {
+		return result
+	}
+
+	err.Stack = append(err.Stack, current)
+	if tailCalls > 0 {
+		err.Stack = append(err.Stack, first)
+	}
+	return err
+}
+
+// atCallSite gives an error raised by making a call the position of the call, which it
+// would otherwise get from the call expression that started the chain of tail calls.
+func atCallSite(result object.Object, callSite token.Position) object.Object {
+	if err, ok := result.(*object.Error); ok && !err.Pos.IsValid() {
+		err.Pos = callSite
+	}
+	return result
+}
+
+func (e *Evaluator) maxDepth() int {
+	if e.MaxDepth > 0 {
+		return e.MaxDepth
+	}
+	return DefaultMaxDepth
+}
+
+// extendedFunctionEnv binds the arguments of a call to the parameters of fn: positional
+// arguments in order, any left over in to its rest parameter, and named arguments by name.
+// Parameters still without a value take their default, which is evaluated in the new
+// environment so that it can refer to the closure and to the parameters before it.
+func (e *Evaluator) extendedFunctionEnv(fn *object.Function, args []object.Object, named []namedArgument) (*object.Environment, object.Object) {
+	if len(args) > len(fn.Parameters) && fn.Rest == nil {
+		return nil, arityError(fn, len(args)+len(named))
+	}
+
+	if err := e.charge(environmentSize + int64(len(fn.Parameters))*bindingSize); err != nil {
+		return nil, err
+	}
 	env := object.NewEnclosedEnvironment(fn.Env)
+
+	values := make([]object.Object, len(fn.Parameters))
+	copy(values, args)
+
+	if fn.Rest != nil {
+		rest := []object.Object{}
+		if len(args) > len(fn.Parameters) {
+			rest = append(rest, args[len(fn.Parameters):]...)
+		}
+		restArray := e.allocate(&object.Array{Elements: rest})
+		if isError(restArray) {
+			return nil, restArray
+		}
+		if err := e.charge(bindingSize); err != nil {
+			return nil, err
+		}
+		env.Set(fn.Rest.Value, restArray)
+	}
+
+	for _, arg := range named {
+		var err *object.Error
+
+		switch i := parameterIndex(fn, arg.name.Value); {
+		case i < 0:
+			err = object.NewError("unknown argument %s", arg.name.Value)
+		case values[i] != nil:
+			err = object.NewError("argument %s given more than once", arg.name.Value)
+		default:
+			values[i] = arg.value
+			continue
+		}
+
+		err.Pos = arg.name.Pos()
+		return nil, err
+	}
+
+	for i, param := range fn.Parameters {
+		val := values[i]
+		if val == nil {
+			value := parameterDefault(fn, i)
+			switch {
+			case value == nil && len(named) == 0:
+				return nil, arityError(fn, len(args))
+			case value == nil:
+				return nil, object.NewError("missing argument %s", param.Value)
+			}
+
+			val = e.Eval(value, env)
+			if isError(val) {
+				return nil, val
+			}
+		}
+		env.Set(param.Value, val)
+	}
+
+	return env, nil
+}
+
+// namedArgument is the value of an argument passed to a parameter by name.
+type namedArgument struct {
+	name  *ast.Identifier
+	value object.Object
+}
+
+// evalArguments evaluates the arguments of a call in order, separating the positional
+// arguments from the named ones. The arguments are held until the caller releases them.
+func (e *Evaluator) evalArguments(exps []ast.Expression, env *object.Environment) ([]object.Object, []namedArgument, object.Object) {
+	args := []object.Object{}
+	var named []namedArgument
+
+	for _, exp := range exps {
+		if arg, ok := exp.(*ast.NamedArgument); ok {
+			val := e.Eval(arg.Value, env)
+			if isError(val) {
+				return nil, nil, val
+			}
+			e.hold(val)
+			named = append(named, namedArgument{name: arg.Name, value: val})
+			continue
+		}
+
+		val := e.Eval(exp, env)
+		if isError(val) {
+			return nil, nil, val
+		}
+		e.hold(val)
+		args = append(args, val)
+	}
+
+	return args, named, nil
+}
+
+// arityError reports a call to fn with got arguments, which is too many or too few.
+func arityError(fn *object.Function, got int) *object.Error {
+	min, max := 0, len(fn.Parameters)
+	for i := range fn.Parameters {
+		if parameterDefault(fn, i) == nil {
+			min += 1
+		}
+	}
+	if fn.Rest != nil {
+		max = -1
+	}
+	return object.NewArityRangeError(fn.Name, min, max, got)
+}
+
+// parameterIndex returns the index of fn's parameter called name, or -1 if it has none.
+func parameterIndex(fn *object.Function, name string) int {
 	for i, param := range fn.Parameters {
-		env.Set(param.Value, args[i])
+		if param.Value == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func parameterDefault(fn *object.Function, i int) ast.Expression {
+	if i < len(fn.Defaults) {
+		return fn.Defaults[i]
+	}
+	return nil
+}
+
+// nameFunction gives a function literal bound by a let or const statement the name it
+// was bound to, so that errors about calls to it can name it.
+func nameFunction(name *ast.Identifier, value ast.Expression, val object.Object) {
+	if _, ok := value.(*ast.FunctionLiteral); !ok {
+		return
 	}
-	return env
+	if fn, ok := val.(*object.Function); ok {
+		fn.Name = name.Value
+	}
+}
+
+// orNull returns NULL for the nil that blocks ending in a statement, such as let,
+// evaluate to, so that the value of a call, an if or a program is always an object.
+func orNull(obj object.Object) object.Object {
+	if obj == nil {
+		return NULL
+	}
+	return obj
 }
 
 func unwrapReturnValue(obj object.Object) object.Object {
@@ -113,14 +729,17 @@ func unwrapReturnValue(obj object.Object) object.Object {
 	return obj
 }
 
-func evalExpressions(exps []ast.Expression, env *object.Environment) []object.Object {
-	var result []object.Object
+// evalExpressions evaluates exps in order, holding each value until the caller releases
+// them.
+func (e *Evaluator) evalExpressions(exps []ast.Expression, env *object.Environment) []object.Object {
+	result := []object.Object{}
 
-	for _, e := range exps {
-		evaluated := Eval(e, env)
+	for _, exp := range exps {
+		evaluated := e.Eval(exp, env)
 		if isError(evaluated) {
 			return []object.Object{evaluated}
 		}
+		e.hold(evaluated)
 		result = append(result, evaluated)
 	}
 
@@ -128,22 +747,27 @@ func evalExpressions(exps []ast.Expression, env *object.Environment) []object.Ob
 }
 
 func evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object {
-	val, ok := env.Get(node.Value)
-	if !ok {
-		return object.NewError("identifier not found: %s", node.Value)
+	if val, ok := env.Get(node.Value); ok {
+		return val
+	}
+
+	if builtin, ok := object.LookupBuiltin(node.Value); ok {
+		return builtin
 	}
-	return val
+
+	return object.NewError("identifier not found: %s", node.Value)
 }
 
-func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) object.Object {
+func (e *Evaluator) evalBlockStatement(block *ast.BlockStatement, env *object.Environment) object.Object {
 	var result object.Object
 
 	for _, stmt := range block.Statements {
-		result = Eval(stmt, env)
+		result = e.Eval(stmt, env)
 
 		if result != nil {
 			rt := result.Type()
-			if rt == object.RETURN_VALUE_OBJ || rt == object.ERROR_OBJ {
+			if rt == object.RETURN_VALUE_OBJ || rt == object.ERROR_OBJ ||
+				rt == object.BREAK_OBJ || rt == object.CONTINUE_OBJ {
 				return result
 			}
 		}
@@ -152,16 +776,144 @@ func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) obje
 	return result
 }
 
-func evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Object {
-	condition := Eval(ie.Condition, env)
+func (e *Evaluator) evalWhileStatement(ws *ast.WhileStatement, env *object.Environment) object.Object {
+	for {
+		condition := e.Eval(ws.Condition, env)
+		if isError(condition) {
+			return condition
+		}
+		if !isTruthy(condition) {
+			return NULL
+		}
+
+		if err := e.charge(environmentSize); err != nil {
+			return err
+		}
+
+		bodyEnv := object.NewEnclosedEnvironment(env)
+		e.enterScope(bodyEnv)
+		result := e.Eval(ws.Body, bodyEnv)
+		e.exitScope()
+
+		if stop, ok := loopResult(result); ok {
+			return stop
+		}
+	}
+}
+
+func (e *Evaluator) evalForStatement(fs *ast.ForStatement, env *object.Environment) object.Object {
+	iterable := e.Eval(fs.Iterable, env)
+	if isError(iterable) {
+		return iterable
+	}
+
+	defer e.release(len(e.pending))
+	e.hold(iterable)
+
+	next, err := iterate(iterable)
+	if err != nil {
+		return err
+	}
+
+	// the characters of a string are new objects, unlike the elements of arrays and keys of hashes
+	_, characters := iterable.(*object.String)
+
+	for element, ok := next(); ok; element, ok = next() {
+		if characters {
+			if err := e.charge(sizeOf(element)); err != nil {
+				return err
+			}
+		}
+		if err := e.charge(environmentSize + bindingSize); err != nil {
+			return err
+		}
+
+		bodyEnv := object.NewEnclosedEnvironment(env)
+		bodyEnv.Set(fs.Variable.Value, element)
+
+		e.enterScope(bodyEnv)
+		result := e.Eval(fs.Body, bodyEnv)
+		e.exitScope()
+
+		if stop, ok := loopResult(result); ok {
+			return stop
+		}
+	}
+
+	return NULL
+}
+
+// loopResult decides what a loop does after its body produced result. It reports true
+// when the loop has to stop, along with what the loop evaluates to.
+func loopResult(result object.Object) (object.Object, bool) {
+	switch result {
+	case object.BREAK:
+		return NULL, true
+	case object.CONTINUE:
+		return nil, false
+	}
+
+	if result != nil {
+		rt := result.Type()
+		if rt == object.RETURN_VALUE_OBJ || rt == object.ERROR_OBJ {
+			return result, true
+		}
+	}
+
+	return nil, false
+}
+
+// iterate returns a func that returns each of the values a for loop visits in turn,
+// reporting false once there are no more: the elements of an array, the characters of a
+// string or the keys of a hash in insertion order. The characters are only created as
+// they are visited.
+func iterate(iterable object.Object) (func() (object.Object, bool), *object.Error) {
+	switch iterable := iterable.(type) {
+	case *object.Array:
+		return each(append([]object.Object{}, iterable.Elements...)), nil
+	case *object.String:
+		rest := iterable.Value
+		return func() (object.Object, bool) {
+			if rest == "" {
+				return nil, false
+			}
+			r, size := utf8.DecodeRuneInString(rest)
+			rest = rest[size:]
+			return &object.String{Value: string(r)}, true
+		}, nil
+	case *object.Hash:
+		keys := make([]object.Object, 0, len(iterable.Pairs))
+		for _, pair := range iterable.Pairs {
+			keys = append(keys, pair.Key)
+		}
+		return each(keys), nil
+	default:
+		return nil, object.NewError("cannot iterate over %s", iterable.Type())
+	}
+}
+
+// each returns a func that returns the objects in turn, for iterate.
+func each(objects []object.Object) func() (object.Object, bool) {
+	i := 0
+	return func() (object.Object, bool) {
+		if i == len(objects) {
+			return nil, false
+		}
+		i += 1
+		return objects[i-1], true
+	}
+}
+
+func (e *Evaluator) evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Object {
+	condition := e.Eval(ie.Condition, env)
 	if isError(condition) {
 		return condition
 	}
 
 	if isTruthy(condition) {
-		return Eval(ie.Consequence, env)
+		return orNull(e.Eval(ie.Consequence, env))
 	} else if ie.Alternative != nil {
-		return Eval(ie.Alternative, env)
+		return orNull(e.Eval(ie.Alternative, env))
 	} else {
 		return NULL
 	}
@@ -187,12 +939,25 @@ func isError(obj object.Object) bool {
 	return false
 }
 
-func evalPrefixExpression(operator string, right object.Object) object.Object {
+// interrupts reports whether obj stops the statement that evaluated it: an error, or a
+// return, break or continue signal from a block evaluated as an expression, which is
+// passed on to the block around the statement.
+func interrupts(obj object.Object) bool {
+	if obj != nil {
+		switch obj.Type() {
+		case object.ERROR_OBJ, object.RETURN_VALUE_OBJ, object.BREAK_OBJ, object.CONTINUE_OBJ:
+			return true
+		}
+	}
+	return false
+}
+
+func (e *Evaluator) evalPrefixExpression(operator string, right object.Object) object.Object {
 	switch operator {
 	case "!":
 		return evalBangOperatorExpression(right)
 	case "-":
-		return evalMinusPrefixOperatorExpression(right)
+		return e.evalMinusPrefixOperatorExpression(right)
 	default:
 		return object.NewError("unknown operator: %s%s", operator, right.Type())
 	}
@@ -211,23 +976,46 @@ func evalBangOperatorExpression(right object.Object) object.Object {
 	}
 }
 
-func evalMinusPrefixOperatorExpression(right object.Object) object.Object {
-	if right.Type() != object.INTEGER_OBJ {
-		return object.NewError("unknown operator: -%s", right.Type())
-	}
-
+func (e *Evaluator) evalMinusPrefixOperatorExpression(right object.Object) object.Object {
 	switch r := right.(type) {
 	case *object.Integer:
+		if r.Value == math.MinInt64 {
+			if e.CheckedArithmetic {
+				return object.NewError("integer overflow: -(%d)", r.Value)
+			}
+			return object.NewInteger(new(big.Int).Neg(big.NewInt(r.Value)))
+		}
 		return &object.Integer{Value: -r.Value}
+	case *object.BigInt:
+		return object.NewInteger(new(big.Int).Neg(r.Value))
+	case *object.Float:
+		return &object.Float{Value: -r.Value}
 	default:
-		return NULL
+		return object.NewError("unknown operator: -%s", right.Type())
+	}
+}
+
+// evalLogicalExpression evaluates && and || given the value of the left operand. The right
+// operand is only evaluated when the left one does not decide the result, and the result
+// is whichever operand decided it rather than a boolean.
+func (e *Evaluator) evalLogicalExpression(node *ast.InfixExpression, left object.Object, env *object.Environment) object.Object {
+	if isTruthy(left) == (node.Operator == "||") {
+		return left
 	}
+
+	return e.Eval(node.Right, env)
 }
 
-func evalInfixExpression(operator string, left, right object.Object) object.Object {
+func (e *Evaluator) evalInfixExpression(operator string, left, right object.Object) object.Object {
 	switch {
 	case left.Type() == object.INTEGER_OBJ && right.Type() == object.INTEGER_OBJ:
-		return evalIntegerInfixExpression(operator, left, right)
+		return e.evalIntegerInfixExpression(operator, left, right)
+	case object.IsInteger(left) && object.IsInteger(right):
+		return evalBigIntInfixExpression(operator, left, right)
+	case object.IsNumber(left) && object.IsNumber(right):
+		return evalFloatInfixExpression(operator, left, right)
+	case left.Type() == object.STRING_OBJ && right.Type() == object.STRING_OBJ:
+		return evalStringInfixExpression(operator, left, right)
 	case operator == "==":
 		return nativeBoolToBooleanObject(left == right)
 	case operator == "!=":
@@ -241,9 +1029,16 @@ func evalInfixExpression(operator string, left, right object.Object) object.Obje
 	}
 }
 
-func evalIntegerInfixExpression(operator string, left, right object.Object) object.Object {
+func (e *Evaluator) evalIntegerInfixExpression(operator string, left, right object.Object) object.Object {
 	leftVal, rightVal := left.(*object.Integer).Value, right.(*object.Integer).Value
 
+	if object.IntegerOverflows(operator, leftVal, rightVal) {
+		if e.CheckedArithmetic {
+			return object.NewError("integer overflow: %d %s %d", leftVal, operator, rightVal)
+		}
+		return evalBigIntInfixExpression(operator, left, right)
+	}
+
 	switch operator {
 	case "+":
 		return &object.Integer{Value: leftVal + rightVal}
@@ -252,11 +1047,117 @@ func evalIntegerInfixExpression(operator string, left, right object.Object) obje
 	case "*":
 		return &object.Integer{Value: leftVal * rightVal}
 	case "/":
+		if rightVal == 0 {
+			return object.NewError("division by zero")
+		}
 		return &object.Integer{Value: leftVal / rightVal}
+	case "%":
+		if rightVal == 0 {
+			return object.NewError("modulo by zero")
+		}
+		return &object.Integer{Value: leftVal % rightVal}
+	case "<":
+		return nativeBoolToBooleanObject(leftVal < rightVal)
+	case ">":
+		return nativeBoolToBooleanObject(leftVal > rightVal)
+	case "<=":
+		return nativeBoolToBooleanObject(leftVal <= rightVal)
+	case ">=":
+		return nativeBoolToBooleanObject(leftVal >= rightVal)
+	case "==":
+		return nativeBoolToBooleanObject(leftVal == rightVal)
+	case "!=":
+		return nativeBoolToBooleanObject(leftVal != rightVal)
+	default:
+		return object.NewError("unknown operator: %s %s %s",
+			left.Type(), operator, right.Type())
+	}
+}
+
+// evalBigIntInfixExpression applies operator to two integers when either of them is a
+// BigInt or the result would overflow an Integer. Results that fit in an int64 are
+// turned back in to an Integer.
+func evalBigIntInfixExpression(operator string, left, right object.Object) object.Object {
+	leftVal, _ := object.BigValue(left)
+	rightVal, _ := object.BigValue(right)
+
+	switch operator {
+	case "+":
+		return object.NewInteger(new(big.Int).Add(leftVal, rightVal))
+	case "-":
+		return object.NewInteger(new(big.Int).Sub(leftVal, rightVal))
+	case "*":
+		return object.NewInteger(new(big.Int).Mul(leftVal, rightVal))
+	case "/":
+		if rightVal.Sign() == 0 {
+			return object.NewError("division by zero")
+		}
+		return object.NewInteger(new(big.Int).Quo(leftVal, rightVal))
+	case "%":
+		if rightVal.Sign() == 0 {
+			return object.NewError("modulo by zero")
+		}
+		return object.NewInteger(new(big.Int).Rem(leftVal, rightVal))
+	case "<":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) < 0)
+	case ">":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) > 0)
+	case "<=":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) <= 0)
+	case ">=":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) >= 0)
+	case "==":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) == 0)
+	case "!=":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) != 0)
+	default:
+		return object.NewError("unknown operator: %s %s %s",
+			left.Type(), operator, right.Type())
+	}
+}
+
+// evalFloatInfixExpression applies operator to two numbers when either of them is a Float,
+// promoting the other one to a Float. Division by zero follows IEEE 754, producing an
+// infinity or NaN rather than an error.
+func evalFloatInfixExpression(operator string, left, right object.Object) object.Object {
+	leftVal, _ := object.FloatValue(left)
+	rightVal, _ := object.FloatValue(right)
+
+	switch operator {
+	case "+":
+		return &object.Float{Value: leftVal + rightVal}
+	case "-":
+		return &object.Float{Value: leftVal - rightVal}
+	case "*":
+		return &object.Float{Value: leftVal * rightVal}
+	case "/":
+		return &object.Float{Value: leftVal / rightVal}
+	case "%":
+		return &object.Float{Value: math.Mod(leftVal, rightVal)}
 	case "<":
 		return nativeBoolToBooleanObject(leftVal < rightVal)
 	case ">":
 		return nativeBoolToBooleanObject(leftVal > rightVal)
+	case "<=":
+		return nativeBoolToBooleanObject(leftVal <= rightVal)
+	case ">=":
+		return nativeBoolToBooleanObject(leftVal >= rightVal)
+	case "==":
+		return nativeBoolToBooleanObject(leftVal == rightVal)
+	case "!=":
+		return nativeBoolToBooleanObject(leftVal != rightVal)
+	default:
+		return object.NewError("unknown operator: %s %s %s",
+			left.Type(), operator, right.Type())
+	}
+}
+
+func evalStringInfixExpression(operator string, left, right object.Object) object.Object {
+	leftVal, rightVal := left.(*object.String).Value, right.(*object.String).Value
+
+	switch operator {
+	case "+":
+		return &object.String{Value: leftVal + rightVal}
 	case "==":
 		return nativeBoolToBooleanObject(leftVal == rightVal)
 	case "!=":
@@ -266,3 +1167,75 @@ func evalIntegerInfixExpression(operator string, left, right object.Object) obje
 			left.Type(), operator, right.Type())
 	}
 }
+
+func evalIndexExpression(left, index object.Object) object.Object {
+	switch {
+	case left.Type() == object.ARRAY_OBJ && index.Type() == object.INTEGER_OBJ:
+		return evalArrayIndexExpression(left, index)
+	case left.Type() == object.HASH_OBJ:
+		return evalHashIndexExpression(left, index)
+	default:
+		return object.NewError("index operator not supported: %s[%s]", left.Type(), index.Type())
+	}
+}
+
+// evalArrayIndexExpression looks up an element of an array. Negative indexes count
+// back from the end, and indexes that are out of range produce null.
+func evalArrayIndexExpression(array, index object.Object) object.Object {
+	elements := array.(*object.Array).Elements
+	idx := index.(*object.Integer).Value
+	length := int64(len(elements))
+
+	if idx < 0 {
+		idx = idx + length
+	}
+
+	if idx < 0 || idx >= length {
+		return NULL
+	}
+
+	return elements[idx]
+}
+
+func evalHashIndexExpression(hash, index object.Object) object.Object {
+	key, ok := index.(object.Hashable)
+	if !ok {
+		return object.NewError("unusable as hash key: %s", index.Type())
+	}
+
+	value, ok := hash.(*object.Hash).Get(key)
+	if !ok {
+		return NULL
+	}
+
+	return value
+}
+
+func (e *Evaluator) evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Object {
+	defer e.release(len(e.pending))
+
+	hash := object.NewHash()
+
+	for _, pair := range node.Pairs {
+		key := e.Eval(pair.Key, env)
+		if isError(key) {
+			return key
+		}
+
+		hashKey, ok := key.(object.Hashable)
+		if !ok {
+			return object.NewError("unusable as hash key: %s", key.Type())
+		}
+		e.hold(key)
+
+		value := e.Eval(pair.Value, env)
+		if isError(value) {
+			return value
+		}
+		e.hold(value)
+
+		hash.Set(hashKey, value)
+	}
+
+	return e.allocate(hash)
+}
@@ -1,379 +1,337 @@
 package evaluator
 
 import (
+	"context"
+	"fmt"
+	"github.com/jacksonopp/monkey/ast"
+	"github.com/jacksonopp/monkey/enginetest"
 	"github.com/jacksonopp/monkey/lexer"
 	"github.com/jacksonopp/monkey/object"
 	"github.com/jacksonopp/monkey/parser"
+	"os"
+	"strings"
 	"testing"
+	"time"
 )
 
-func TestExpressions(t *testing.T) {
-	t.Run("integer expression", func(t *testing.T) {
-		tests := []struct {
-			input    string
-			expected int64
+func TestEngineCases(t *testing.T) {
+	enginetest.Run(t, func(t *testing.T, input string) object.Object {
+		return testEval(t, input)
+	})
+}
+
+func TestShortCircuitSkipsUndefinedIdentifiers(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"false && missing", false},
+		{"true || missing", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			testBooleanObject(t, testEval(t, tt.input), tt.expected)
+		})
+	}
+}
+
+func TestHashes(t *testing.T) {
+	t.Run("hash literal", func(t *testing.T) {
+		input := `let two = "two";
+{
+	"one": 10 - 9,
+	two: 1 + 1,
+	"thr" + "ee": 6 / 2,
+	4: 4,
+	true: 5,
+	false: 6
+}`
+
+		evaluated := testEval(t, input)
+		result, ok := evaluated.(*object.Hash)
+		if !ok {
+			t.Fatalf("Eval didn't return Hash. got=%T (%+v)", evaluated, evaluated)
+		}
+
+		expected := []struct {
+			key   object.Hashable
+			value int64
 		}{
-			{
-				"5",
-				5,
-			},
-			{
-				"10",
-				10,
-			},
-			{
-				"-5",
-				-5,
-			},
-			{
-				"5 + 5",
-				5 + 5,
-			},
-			{
-				"5 * 5",
-				5 * 5,
-			},
-			{
-				"5 + 2 * 2",
-				5 + 2*2,
-			},
-			{
-				"50 / 5 - 3",
-				50/5 - 3,
-			},
-			{
-				"2 * (3+4)",
-				2 * (3 + 4),
-			},
-			{
-				"-30 + 10",
-				-30 + 10,
-			},
+			{&object.String{Value: "one"}, 1},
+			{&object.String{Value: "two"}, 2},
+			{&object.String{Value: "three"}, 3},
+			{&object.Integer{Value: 4}, 4},
+			{TRUE, 5},
+			{FALSE, 6},
 		}
 
-		for _, tt := range tests {
-			t.Run(tt.input, func(t *testing.T) {
-				evaluated := testEval(tt.input)
-				testIntegerObject(t, evaluated, tt.expected)
-			})
+		if len(result.Pairs) != len(expected) {
+			t.Fatalf("hash has wrong number of pairs. got=%d", len(result.Pairs))
 		}
-	})
 
-	t.Run("boolean expressions", func(t *testing.T) {
-		tests := []struct {
-			input    string
-			expected bool
-		}{
-			{
-				"true",
-				true,
-			},
-			{
-				"false",
-				false,
-			},
-			{
-				"1 < 2",
-				true,
-			},
-			{
-				"1 > 2",
-				false,
-			},
-			{
-				"1 == 1",
-				true,
-			},
-			{
-				"1 != 1",
-				false,
-			},
-			{
-				"1 != 2",
-				true,
-			},
-			{
-				"1 == 2",
-				false,
-			},
-			{
-				"true == true",
-				true,
-			},
-			{
-				"false == false",
-				true,
-			},
-			{
-				"true == false",
-				false,
-			},
-			{
-				"true != false",
-				true,
-			},
-			{
-				"false != true",
-				true,
-			},
-			{
-				"(1 < 2) == true",
-				true,
-			},
-			{
-				"(1 > 2) == true",
-				false,
-			}, {
-				"(1 < 2) == false",
-				false,
-			},
-			{
-				"(1 > 2) == false",
-				true,
-			},
+		for i, tt := range expected {
+			if result.Pairs[i].Key.Inspect() != tt.key.(object.Object).Inspect() {
+				t.Errorf("key %d out of order", i)
+			}
+
+			value, ok := result.Get(tt.key)
+			if !ok {
+				t.Errorf("no pair for given key in Pairs")
+				continue
+			}
+			testIntegerObject(t, value, tt.value)
 		}
+	})
+}
 
-		for _, tt := range tests {
-			t.Run(tt.input, func(t *testing.T) {
-				evaluated := testEval(tt.input)
-				testBooleanObject(t, evaluated, tt.expected)
-			})
+func TestRegisterBuiltin(t *testing.T) {
+	object.RegisterBuiltin("double", func(args ...object.Object) object.Object {
+		integer, ok := args[0].(*object.Integer)
+		if !ok {
+			return object.NewError("argument to `double` must be INTEGER, got %s", args[0].Type())
 		}
+		return &object.Integer{Value: integer.Value * 2}
 	})
+	t.Cleanup(func() { object.UnregisterBuiltin("double") })
 
-	t.Run("bang operator", func(t *testing.T) {
-		tests := []struct {
-			name     string
-			input    string
-			expected bool
-		}{
-			{
-				"not true",
-				"!true",
-				false,
-			},
-			{
-				"not false",
-				"!false",
-				true,
-			},
-			{
-				"not 5",
-				"!5",
-				false,
-			},
-			{
-				"not not true",
-				"!!true",
-				true,
-			},
-			{
-				"not not false",
-				"!!false",
-				false,
-			},
-			{
-				"not not 5",
-				"!!5",
-				true,
-			},
+	t.Run("call registered builtin", func(t *testing.T) {
+		testIntegerObject(t, testEval(t, "double(21)"), 42)
+	})
+
+	t.Run("let shadows builtin", func(t *testing.T) {
+		testIntegerObject(t, testEval(t, "let double = fn(x) { x }; double(21)"), 21)
+	})
+
+	t.Run("builtin as value", func(t *testing.T) {
+		testIntegerObject(t, testEval(t, "let apply = fn(f, x) { f(x) }; apply(double, 2)"), 4)
+	})
+
+	t.Run("lookup registered builtin", func(t *testing.T) {
+		builtin, ok := object.LookupBuiltin("double")
+		if !ok {
+			t.Fatalf("builtin double not registered")
 		}
+		if builtin.Name != "double" {
+			t.Errorf("builtin.Name wrong. got=%q", builtin.Name)
+		}
+	})
 
-		for _, tt := range tests {
-			t.Run(tt.name, func(t *testing.T) {
-				evaluated := testEval(tt.input)
-				testBooleanObject(t, evaluated, tt.expected)
-			})
+	t.Run("unregister builtin", func(t *testing.T) {
+		object.RegisterBuiltin("triple", func(args ...object.Object) object.Object { return object.NULL })
+		object.UnregisterBuiltin("triple")
+
+		if _, ok := object.LookupBuiltin("triple"); ok {
+			t.Errorf("builtin triple still registered")
 		}
 	})
 
-	t.Run("if else expressions", func(t *testing.T) {
-		tests := []struct {
-			name     string
-			input    string
-			expected interface{}
-		}{
-			{
-				"true",
-				"if (true) { 10 }",
-				10,
-			},
-			{
-				"false",
-				"if (false) { 10 }",
-				nil,
-			},
-			{
-				"truthy",
-				"if (1) { 10 }",
-				10,
-			},
-			{
-				"comparison true",
-				"if (1 < 10) { 10 }",
-				10,
-			},
-			{
-				"comparison false",
-				"if (1 > 10) { 10 }",
-				nil,
-			},
-			{
-				"comparison else is true",
-				"if (1 > 10) { 10 } else { 20 }",
-				20,
-			},
-			{
-				"comparison false",
-				"if (1 < 10) { 10 } else { 20 }",
-				10,
-			},
+	t.Run("puts writes to the output", func(t *testing.T) {
+		var out strings.Builder
+		object.SetOutput(&out)
+		t.Cleanup(func() { object.SetOutput(os.Stdout) })
+
+		testEval(t, `puts("hello", 5)`)
+
+		if out.String() != "hello\n5\n" {
+			t.Errorf("wrong output. got=%q", out.String())
 		}
+	})
+}
 
-		for _, tt := range tests {
-			t.Run(tt.name, func(t *testing.T) {
-				evaluated := testEval(tt.input)
-				integer, ok := tt.expected.(int)
-				if ok {
-					testIntegerObject(t, evaluated, int64(integer))
-				} else {
-					testNullObject(t, evaluated)
+func TestLoops(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected interface{}
+	}{
+		{"return from while", "let f = fn() { while (true) { return 7; } }; f()", 7},
+		{"while evaluates to null", "while (false) { 1 }", nil},
+		{"while condition error", "while (1 + true) { 1 }", "type mismatch: INTEGER + BOOLEAN"},
+		{"for over array", `let f = fn() { for (x in [1, 2, 3]) { if (x > 1) { return x } } }; f()`, 2},
+		{"for over string", `let f = fn() { for (c in "héllo") { if (c != "h") { return c } } }; f()`, "é"},
+		{"for over hash keys", `let f = fn() { for (k in {"b": 1, "a": 2}) { return k } }; f()`, "b"},
+		{"for over empty array", "for (x in []) { 1 + true }", nil},
+		{"break stops the loop", `let f = fn() { for (x in [1, 2, 3]) { if (x == 2) { break; } return x } }; f()`, 1},
+		{"break from while", "let f = fn() { while (true) { break; } 5 }; f()", 5},
+		{"continue skips the rest", `let f = fn() { for (x in [1, 2, 3]) { if (x < 3) { continue; } return x } }; f()`, 3},
+		{"break in nested loop", `let f = fn() { for (x in [1, 2]) { for (y in [3, 4]) { break; } return x } }; f()`, 1},
+		{"loop variable does not leak", "let x = 10; for (x in [1, 2]) { x }; x", 10},
+		{"let in body does not leak", "let y = 1; for (x in [1]) { let y = 2; }; y", 1},
+		{"error in body", "for (x in [1]) { x + true }", "type mismatch: INTEGER + BOOLEAN"},
+		{"not iterable", "for (x in 5) { x }", "cannot iterate over INTEGER"},
+		{"break in let", "let f = fn() { let n = 0; while (true) { let x = if (n == 2) { break; }; n = n + 1; } n }; f()", 2},
+		{"continue in let", "let f = fn() { let n = 0; for (i in [1, 2, 3]) { let x = if (i == 2) { continue; }; n = n + i; } n }; f()", 4},
+		{"break in const", "let f = fn() { for (i in [1, 2]) { const x = if (true) { break; }; return 1 } 2 }; f()", 2},
+		{"break in assignment", "let f = fn() { let x = 0; while (true) { x = if (true) { break; }; return 1 } x }; f()", 0},
+		{"break in index assignment", "let f = fn() { let a = [0]; while (true) { a[0] = if (true) { break; }; } a[0] }; f()", 0},
+		{"return in let", "let f = fn() { let x = if (true) { return 3; }; 4 }; f()", 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			evaluated := testEval(t, tt.input)
+
+			switch expected := tt.expected.(type) {
+			case int:
+				testIntegerObject(t, evaluated, int64(expected))
+			case nil:
+				testNullObject(t, evaluated)
+			case string:
+				if errObj, ok := evaluated.(*object.Error); ok {
+					if errObj.Message != expected {
+						t.Errorf("wrong error message. want=%q, got=%q", expected, errObj.Message)
+					}
+					return
 				}
-			})
+				testStringObject(t, evaluated, expected)
+			}
+		})
+	}
+}
+
+func TestAssignment(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected interface{}
+	}{
+		{"reassign", "let x = 1; x = 2; x", 2},
+		{"assignment evaluates to value", "let x = 1; x = 5", 5},
+		{"chained", "let a = 1; let b = 2; a = b = 3; a + b", 6},
+		{"plus assign", "let x = 1; x += 2; x", 3},
+		{"minus assign", "let x = 1; x -= 2; x", -1},
+		{"times assign", "let x = 3; x *= 4; x", 12},
+		{"divide assign", "let x = 9; x /= 2; x", 4},
+		{"string plus assign", `let s = "a"; s += "b"; s`, "ab"},
+		{"updates outer binding", "let x = 1; let f = fn() { x = x + 1; }; f(); f(); x", 3},
+		{"updates nearest binding", "let x = 1; let f = fn() { let x = 10; x = 20; x }; f() + x", 21},
+		{"closure counter", "let counter = fn() { let n = 0; fn() { n += 1 } }; let c = counter(); c(); c(); c()", 3},
+		{"while with counter", "let i = 0; let sum = 0; while (i < 5) { i += 1; sum += i; } sum", 15},
+		{"array element", "let a = [1, 2, 3]; a[0] = 10; a[0]", 10},
+		{"negative array index", "let a = [1, 2, 3]; a[-1] += 5; a[2]", 8},
+		{"array is shared", "let a = [1]; let b = a; b[0] = 5; a[0]", 5},
+		{"hash existing key", `let h = {"a": 1}; h["a"] = 2; h["a"]`, 2},
+		{"hash new key", `let h = {}; h["b"] = 3; h["b"]`, 3},
+		{"hash compound", `let h = {"n": 1}; h["n"] *= 7; h["n"]`, 7},
+		{"undeclared", "y = 1", "assignment to undeclared identifier: y"},
+		{"undeclared compound", "y += 1", "identifier not found: y"},
+		{"array index out of range", "let a = [1]; a[1] = 2", "index out of range: 1"},
+		{"unhashable key", "let h = {}; h[fn() {}] = 1", "unusable as hash key: FUNCTION"},
+		{"unsupported index target", "let s = \"abc\"; s[0] = \"x\"", "index assignment not supported: STRING[INTEGER]"},
+		{"compound type mismatch", "let x = 1; x += true", "type mismatch: INTEGER + BOOLEAN"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			evaluated := testEval(t, tt.input)
+
+			switch expected := tt.expected.(type) {
+			case int:
+				testIntegerObject(t, evaluated, int64(expected))
+			case string:
+				if errObj, ok := evaluated.(*object.Error); ok {
+					if errObj.Message != expected {
+						t.Errorf("wrong error message. want=%q, got=%q", expected, errObj.Message)
+					}
+					return
+				}
+				testStringObject(t, evaluated, expected)
+			}
+		})
+	}
+
+	t.Run("hash keeps insertion order", func(t *testing.T) {
+		evaluated := testEval(t, `let h = {"a": 1, "b": 2}; h["a"] = 3; h["c"] = 4; h`)
+		if evaluated.Inspect() != "{a: 3, b: 2, c: 4}" {
+			t.Errorf("wrong Inspect. got=%q", evaluated.Inspect())
 		}
 	})
 
-	t.Run("function expression", func(t *testing.T) {
-		tests := []struct {
-			name     string
-			input    string
-			expected int64
-		}{
-			{
-				"identity",
-				"let identity = fn(x) { x; }; identity(5);",
-				5,
-			},
-			{
-				"identity with return",
-				"let identity = fn(x) { return x; }; identity(5);",
-				5,
-			},
-			{
-				"double",
-				"let double = fn(x) { x * 2; }; double(5);",
-				10,
-			},
-			{
-				"add",
-				"let add = fn(x, y) { x + y; }; add(5, 5);",
-				10,
-			},
-			{
-				"add with calling add",
-				"let add = fn(x, y) { x + y; }; add(5, add(5, 5));",
-				15,
-			},
-			{
-				"anonymous iife",
-				"fn(x){ x; }(5);",
-				5,
-			},
-			{
-				"closure",
-				`
-let newAdder = fn(x) {
-  fn(y) { x + y };
-};
-
-let addTwo = newAdder(2);
-
-addTwo(2);
-`,
-				4,
-			},
-		}
+	t.Run("contains itself", func(t *testing.T) {
+		var out strings.Builder
+		object.SetOutput(&out)
+		t.Cleanup(func() { object.SetOutput(os.Stdout) })
 
-		for _, tt := range tests {
-			t.Run(tt.name, func(t *testing.T) {
-				testIntegerObject(t, testEval(tt.input), tt.expected)
-			})
+		evaluated := testEval(t, `let a = [1]; a[0] = a; let h = {}; h["h"] = h; h["a"] = a; puts(a); h`)
+		if evaluated.Inspect() != "{h: {...}, a: [[...]]}" {
+			t.Errorf("wrong Inspect. got=%q", evaluated.Inspect())
+		}
+		if out.String() != "[[...]]\n" {
+			t.Errorf("wrong output. got=%q", out.String())
 		}
 	})
 }
 
-func TestStatements(t *testing.T) {
-	t.Run("return statement", func(t *testing.T) {
+func TestConstants(t *testing.T) {
+	t.Run("const statement", func(t *testing.T) {
 		tests := []struct {
-			name     string
 			input    string
 			expected int64
 		}{
-			{"basic return", "return 10;", 10},
-			{"not post return", "return 10; 9;", 10},
-			{"not post return, with calculation", "return 2 * 5; 9;", 10},
-			{"not pre return or post return", "9; return 2 * 5; 9;", 10},
-			{
-				"inside a nested statement",
-				`
-				if (10 > 1) {
-				  if (10 > 1) {
-					return 10;
-				  }
-				  return 1;
-				}
-				`,
-				10,
-			},
+			{"const x = 5; x", 5},
+			{"const x = 5; let f = fn() { x * 2 }; f()", 10},
+			{"const x = 5; let f = fn(x) { x = x + 1; x }; f(1) + x", 7},
+			{"const a = [1]; a[0] = 2; a[0]", 2},
 		}
 
 		for _, tt := range tests {
 			t.Run(tt.input, func(t *testing.T) {
-				evaluated := testEval(tt.input)
-				testIntegerObject(t, evaluated, tt.expected)
+				testIntegerObject(t, testEval(t, tt.input), tt.expected)
 			})
 		}
 	})
 
-	t.Run("let statement", func(t *testing.T) {
+	// these are caught when each program runs, because the parser only sees one at a time
+	t.Run("runtime errors", func(t *testing.T) {
 		tests := []struct {
 			name     string
-			input    string
-			expected int64
+			inputs   []string
+			expected string
 		}{
-			{
-				"simple assignment",
-				"let a = 10; a;",
-				10,
-			},
-			{
-				"simple assignment with calculation",
-				"let a = 5 * 2; a;",
-				10,
-			},
-			{
-				"multiple assignments",
-				"let a = 10; let b = a; b;",
-				10,
-			},
-			{
-				"multiple assignments with calculations",
-				"let a = 5; let b = 3; let c = a * b - 5; c;",
-				10,
-			},
+			{"assign", []string{"const x = 1;", "x = 2;"}, "ERROR: 1:3: cannot assign to constant x"},
+			{"assign from function", []string{"const x = 1;", "let f = fn() { x += 1 }; f()"}, "ERROR: 1:18: cannot assign to constant x"},
+			{"redeclare with let", []string{"const x = 1;", "let x = 2;"}, "ERROR: 1:1: cannot redeclare constant x"},
+			{"redeclare with const", []string{"const x = 1;", "const x = 2;"}, "ERROR: 1:1: cannot redeclare constant x"},
 		}
+
 		for _, tt := range tests {
 			t.Run(tt.name, func(t *testing.T) {
-				testIntegerObject(t, testEval(tt.input), tt.expected)
+				env := object.NewEnvironment()
+
+				var evaluated object.Object
+				for _, input := range tt.inputs {
+					p := parser.New(lexer.New(input))
+					program := p.ParseProgram()
+					if len(p.Errors()) > 0 {
+						t.Fatalf("parser errors: %v", p.Errors())
+					}
+					evaluated = Eval(program, env)
+				}
+
+				errObj, ok := evaluated.(*object.Error)
+				if !ok {
+					t.Fatalf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+				}
+				if errObj.Inspect() != tt.expected {
+					t.Errorf("wrong error. want=%q, got=%q", tt.expected, errObj.Inspect())
+				}
 			})
 		}
 	})
+
+	t.Run("let rebinding is unchanged", func(t *testing.T) {
+		env := object.NewEnvironment()
+		Eval(parse(t, "let x = 1;"), env)
+		evaluated := Eval(parse(t, "let x = 2; x"), env)
+		testIntegerObject(t, evaluated, 2)
+	})
 }
 
 func TestFunctionObject(t *testing.T) {
 	input := "fn(x) {x + 2};"
 
-	evaluated := testEval(input)
+	evaluated := testEval(t, input)
 	fn, ok := evaluated.(*object.Function)
 
 	if !ok {
@@ -395,79 +353,879 @@ func TestFunctionObject(t *testing.T) {
 	}
 }
 
+func TestFunctionArguments(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected interface{}
+	}{
+		{"default used", "let f = fn(a, b = 10) { a + b }; f(1)", 11},
+		{"default replaced", "let f = fn(a, b = 10) { a + b }; f(1, 2)", 3},
+		{"default refers to earlier parameter", "let f = fn(a, b = a * 2) { a + b }; f(3)", 9},
+		{"default evaluated at call time", "let n = 1; let f = fn(a = n) { a }; n = 5; f()", 5},
+		{"default evaluated in closure", "let make = fn(n) { fn(a = n) { a } }; let n = 100; make(7)()", 7},
+		{"default evaluated for each call", "let calls = 0; let count = fn() { calls += 1 }; let f = fn(a = count()) { a }; f(); f(); calls", 2},
+		{"rest collects extra arguments", "let f = fn(a, ...rest) { len(rest) }; f(1, 2, 3)", 2},
+		{"rest holds the arguments", "let f = fn(a, ...rest) { rest[1] }; f(1, 2, 3)", 3},
+		{"rest is empty without extras", "let f = fn(...rest) { len(rest) }; f()", 0},
+		{"named arguments", "let f = fn(a, b) { a - b }; f(b: 2, a: 10)", 8},
+		{"named after positional", "let f = fn(a, b, c) { a - b - c }; f(10, c: 1, b: 2)", 7},
+		{"named skips a default", "let f = fn(a, b = 2, c = 3) { a + b * c }; f(1, c: 10)", 21},
+		{"unknown named argument", "let f = fn(a) { a }; f(b: 1)", "unknown argument b"},
+		{"named argument given twice", "let f = fn(a, b) { a }; f(1, a: 2)", "argument a given more than once"},
+		{"missing argument", "let f = fn(a, b) { a }; f(b: 1)", "missing argument a"},
+		{"rest cannot be named", "let f = fn(...rest) { rest }; f(rest: 1)", "unknown argument rest"},
+		{"too few with defaults", "let f = fn(a, b = 1) { a }; f()", "wrong number of arguments to `f`: want=1..2, got=0"},
+		{"too many with defaults", "let f = fn(a, b = 1) { a }; f(1, 2, 3)", "wrong number of arguments to `f`: want=1..2, got=3"},
+		{"too few with rest", "let f = fn(a, ...rest) { a }; f()", "wrong number of arguments to `f`: want>=1, got=0"},
+		{"error in default", "let f = fn(a = 1 + true) { a }; f()", "type mismatch: INTEGER + BOOLEAN"},
+		{"named argument to builtin", `len(s: "abc")`, "builtin `len` does not take named arguments"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			evaluated := testEval(t, tt.input)
+
+			switch expected := tt.expected.(type) {
+			case int:
+				testIntegerObject(t, evaluated, int64(expected))
+			case string:
+				errObj, ok := evaluated.(*object.Error)
+				if !ok {
+					t.Fatalf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+				}
+				if errObj.Message != expected {
+					t.Errorf("wrong error message. want=%q, got=%q", expected, errObj.Message)
+				}
+			}
+		})
+	}
+}
+
 func TestErrorHandling(t *testing.T) {
-	t.Run("error handling", func(t *testing.T) {
-		tests := []struct {
-			name            string
-			input           string
-			expectedMessage string
-		}{
-			{
-				"type mismatch",
-				"5 + true",
-				"type mismatch: INTEGER + BOOLEAN",
-			},
-			{
-				"adding int and bool",
-				"5 + true; 8;",
-				"type mismatch: INTEGER + BOOLEAN",
+	tests := []struct {
+		name            string
+		input           string
+		expectedMessage string
+	}{
+		{
+			"identifier not found",
+			"foobar",
+			"identifier not found: foobar",
+		},
+		{
+			"wrong number of arguments to a constant function",
+			"const double = fn(x) { x * 2 }; double();",
+			"wrong number of arguments to `double`: want=1, got=0",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			evaluated := testEval(t, tt.input)
+
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Fatalf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+			}
+			if errObj.Message != tt.expectedMessage {
+				t.Errorf("wrong error message. want=%q, got=%q", tt.expectedMessage, errObj.Message)
+			}
+		})
+	}
+}
+
+func TestErrorPositions(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"infix operator", "let x = 5;\nx + true;", "ERROR: test.mk:2:3: type mismatch: INTEGER + BOOLEAN"},
+		{"prefix operator", "-true", "ERROR: test.mk:1:1: unknown operator: -BOOLEAN"},
+		{"identifier", "let f = fn() {\n  foobar\n};\nf()", "ERROR: test.mk:2:3: identifier not found: foobar"},
+		{"builtin call", "len(1)", "ERROR: test.mk:1:4: argument to `len` not supported, got INTEGER"},
+		{"division by zero", "let f = fn(x) {\n  10 / x\n};\nf(0)", "ERROR: test.mk:2:6: division by zero"},
+		{"unknown named argument", "let f = fn(x) { x };\nf(1, y: 2)", "ERROR: test.mk:2:6: unknown argument y"},
+		{"wrong number of arguments", "let f = fn(x) { x };\nlet y = 1 + f(1, 2);", "ERROR: test.mk:2:14: wrong number of arguments to `f`: want=1, got=2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := lexer.NewFile("test.mk", tt.input)
+			p := parser.New(l)
+			program := p.ParseProgram()
+			evaluated := Eval(program, object.NewEnvironment())
+
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Fatalf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+			}
+			if errObj.Inspect() != tt.expected {
+				t.Errorf("wrong error. want=%q, got=%q", tt.expected, errObj.Inspect())
+			}
+		})
+	}
+}
+
+func TestStackTraces(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []string // the frames as `function pos args`, followed by `tail` for tail calls
+	}{
+		{
+			"top level error",
+			"1 + true",
+			nil,
+		},
+		{
+			"nested calls",
+			"let inner = fn(a, b) {\n  a / b\n};\nlet outer = fn(x) {\n  inner(x, 0)\n};\nouter(1)",
+			[]string{
+				"inner test.mk:5:8 2",
+				"outer test.mk:7:6 1",
 			},
-			{
-				"negative true",
-				"-true",
-				"unknown operator: -BOOLEAN",
+		},
+		{
+			"chain of tail calls",
+			"let inner = fn() { 1 / 0 };\nlet middle = fn() { inner() };\nlet outer = fn() { middle() };\nouter()",
+			[]string{
+				"inner test.mk:2:26 0 tail",
+				"outer test.mk:4:6 0",
 			},
-			{
-				"adding bools",
-				"true + true",
-				"unknown operator: BOOLEAN + BOOLEAN",
+		},
+		{
+			"anonymous function with named argument",
+			"fn(a) { a + true }(a: 1)",
+			[]string{
+				" test.mk:1:19 1",
 			},
-			{
-				"adding bools nested",
-				"if (20 > 1) { true + false }",
-				"unknown operator: BOOLEAN + BOOLEAN",
+		},
+		{
+			"wrong arguments are reported at the call",
+			"let f = fn(a) { a };\nlet g = fn() { f() };\ng()",
+			[]string{
+				"g test.mk:3:2 0",
 			},
-			{
-				"adding bools deep nested",
-				`
-				if (10 > 1) {
-				  if (10 > 1) {
-					return true + false;
-				  }
-				return 1;
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			program := parser.New(lexer.NewFile("test.mk", tt.input)).ParseProgram()
+			evaluated := Eval(program, object.NewEnvironment())
+
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Fatalf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+			}
+			if len(errObj.Stack) != len(tt.expected) {
+				t.Fatalf("wrong number of frames. want=%d, got=%d", len(tt.expected), len(errObj.Stack))
+			}
+			for i, frame := range errObj.Stack {
+				got := fmt.Sprintf("%s %s %d", frame.Function, frame.Pos, frame.Args)
+				if frame.TailCall {
+					got += " tail"
+				}
+				if got != tt.expected[i] {
+					t.Errorf("frame %d wrong. want=%q, got=%q", i, tt.expected[i], got)
+				}
+			}
+		})
+	}
+
+	t.Run("traceback", func(t *testing.T) {
+		input := "let inner = fn(a, b) {\n  a / b\n};\nlet outer = fn(x) {\n  inner(x, 0)\n};\nouter(1)"
+		expected := "Traceback (most recent call last):\n" +
+			"  test.mk:7:6: in <program>, called `outer` with 1 argument\n" +
+			"  test.mk:5:8: in `outer`, called `inner` with 2 arguments\n" +
+			"ERROR: test.mk:2:5: division by zero"
+
+		program := parser.New(lexer.NewFile("test.mk", input)).ParseProgram()
+		evaluated := Eval(program, object.NewEnvironment())
+
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Fatalf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+		}
+		if errObj.Traceback() != expected {
+			t.Errorf("wrong traceback. want=%q, got=%q", expected, errObj.Traceback())
+		}
+	})
+}
+
+func TestRecursionDepth(t *testing.T) {
+	countdown := "let f = fn(n) { if (n == 0) { 0 } else { 1 + f(n - 1) } };"
+
+	tests := []struct {
+		name     string
+		maxDepth int
+		input    string
+		expected interface{}
+	}{
+		{"within the limit", 10, countdown + "f(9)", 9},
+		{"past the limit", 10, countdown + "f(10)", "maximum recursion depth exceeded in `f`"},
+		{"default limit", 0, countdown + "f(5000)", 5000},
+		{"runaway recursion", 0, "let f = fn(n) { 1 + f(n + 1) }; f(0)", "maximum recursion depth exceeded in `f`"},
+		{"anonymous function", 10, "let apply = fn(g) { 1 + g(g) }; apply(fn(g) { 1 + g(g) })", "maximum recursion depth exceeded"},
+		{"recursion through defaults", 10, "let f = fn(a = f()) { a }; f()", "maximum recursion depth exceeded in `f`"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			program := parse(t, tt.input)
+			ev := New()
+			ev.MaxDepth = tt.maxDepth
+			evaluated := ev.Eval(program, object.NewEnvironment())
+
+			switch expected := tt.expected.(type) {
+			case int:
+				testIntegerObject(t, evaluated, int64(expected))
+			case string:
+				errObj, ok := evaluated.(*object.Error)
+				if !ok {
+					t.Fatalf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+				}
+				if errObj.Message != expected {
+					t.Errorf("wrong error message. want=%q, got=%q", expected, errObj.Message)
+				}
+			}
+		})
+	}
+
+	t.Run("depth is restored after an error", func(t *testing.T) {
+		ev := New()
+		ev.MaxDepth = 10
+		env := object.NewEnvironment()
+
+		ev.Eval(parse(t, countdown+"f(10)"), env)
+		evaluated := ev.Eval(parse(t, "f(9)"), env)
+		testIntegerObject(t, evaluated, 9)
+	})
+
+	t.Run("traceback collapses the recursion", func(t *testing.T) {
+		ev := New()
+		ev.MaxDepth = 5
+		program := parse(t, countdown+"f(10)")
+
+		errObj, ok := ev.Eval(program, object.NewEnvironment()).(*object.Error)
+		if !ok {
+			t.Fatalf("no error object returned")
+		}
+
+		expected := "Traceback (most recent call last):\n" +
+			"  1:60: in <program>, called `f` with 1 argument\n" +
+			"  1:47: in `f`, called `f` with 1 argument\n" +
+			"  [previous line repeated 3 more times]\n" +
+			"ERROR: 1:47: maximum recursion depth exceeded in `f`"
+		if errObj.Traceback() != expected {
+			t.Errorf("wrong traceback. want=%q, got=%q", expected, errObj.Traceback())
+		}
+	})
+}
+
+func TestTailCalls(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected interface{}
+	}{
+		{"countdown", "let f = fn(n) { if (n == 0) { 0 } else { f(n - 1) } }; f(1000000)", 0},
+		{"accumulator", "let sum = fn(n, acc) { if (n == 0) { return acc; } sum(n - 1, acc + n) }; sum(1000000, 0)", 500000500000},
+		{"return in a loop", "let f = fn(n) { while (true) { if (n == 0) { return 7 } return f(n - 1) } }; f(100000)", 7},
+		{"mutual recursion", "let even = fn(n) { if (n == 0) { true } else { odd(n - 1) } }; let odd = fn(n) { if (n == 0) { false } else { even(n - 1) } }; if (even(100001)) { 1 } else { 0 }", 0},
+		{"default and named arguments", "let f = fn(n, acc = 0) { if (n == 0) { acc } else { f(acc: acc + 1, n: n - 1) } }; f(100000)", 100000},
+		{"tail call to a builtin", `let f = fn(s) { len(s) }; f("four")`, 4},
+		{"tail call to a closure", "let add = fn(a) { fn(b) { a + b } }; let f = fn() { add(1)(2) }; f()", 3},
+		{"call that is not in tail position", "let f = fn(n) { if (n == 0) { 0 } else { 1 + f(n - 1) } }; f(1000000)", "maximum recursion depth exceeded in `f`"},
+		{"wrong arguments in a tail call", "let g = fn(a) { a }; let f = fn() { g() }; f()", "wrong number of arguments to `g`: want=1, got=0"},
+		{"error in a tail call to a builtin", "let f = fn() { len(1) }; f()", "argument to `len` not supported, got INTEGER"},
+		{"tail call to something else", "let f = fn() { 5() }; f()", "not a function: INTEGER"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			program := parse(t, tt.input)
+			ev := New()
+			ev.MaxDepth = 100
+			evaluated := ev.Eval(program, object.NewEnvironment())
+
+			switch expected := tt.expected.(type) {
+			case int:
+				testIntegerObject(t, evaluated, int64(expected))
+			case string:
+				errObj, ok := evaluated.(*object.Error)
+				if !ok {
+					t.Fatalf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+				}
+				if errObj.Message != expected {
+					t.Errorf("wrong error message. want=%q, got=%q", expected, errObj.Message)
 				}
-				`,
-				"unknown operator: BOOLEAN + BOOLEAN",
+			}
+		})
+	}
+
+	t.Run("traceback", func(t *testing.T) {
+		input := "let g = fn(n) {\n  n / 0\n};\nlet f = fn(n) {\n  if (n == 0) { g(n) } else { f(n - 1) }\n};\nf(3)"
+		expected := "Traceback (most recent call last):\n" +
+			"  test.mk:7:2: in <program>, called `f` with 1 argument\n" +
+			"  (...tail calls...)\n" +
+			"  test.mk:5:18: called `g` with 1 argument\n" +
+			"ERROR: test.mk:2:5: division by zero"
+
+		program := parser.New(lexer.NewFile("test.mk", input)).ParseProgram()
+		errObj, ok := Eval(program, object.NewEnvironment()).(*object.Error)
+		if !ok {
+			t.Fatalf("no error object returned")
+		}
+		if errObj.Traceback() != expected {
+			t.Errorf("wrong traceback. want=%q, got=%q", expected, errObj.Traceback())
+		}
+	})
+
+	t.Run("error positions", func(t *testing.T) {
+		input := "let g = fn(a) { a };\nlet f = fn() {\n  g()\n};\nf()"
+		program := parser.New(lexer.NewFile("test.mk", input)).ParseProgram()
+		errObj, ok := Eval(program, object.NewEnvironment()).(*object.Error)
+		if !ok {
+			t.Fatalf("no error object returned")
+		}
+		expected := "ERROR: test.mk:3:4: wrong number of arguments to `g`: want=1, got=0"
+		if errObj.Inspect() != expected {
+			t.Errorf("wrong error. want=%q, got=%q", expected, errObj.Inspect())
+		}
+	})
+}
+
+func TestEvalContext(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name            string
+		ctx             func() (context.Context, context.CancelFunc)
+		maxSteps        int
+		input           string
+		expectedMessage string
+		expectedCause   error
+	}{
+		{
+			"timeout",
+			func() (context.Context, context.CancelFunc) {
+				return context.WithTimeout(context.Background(), 10*time.Millisecond)
 			},
-			{
-				"identifier not found",
-				"foobar",
-				"identifier not found: foobar",
+			0,
+			"while (true) {}",
+			"evaluation timed out",
+			context.DeadlineExceeded,
+		},
+		{
+			"cancelled while running",
+			func() (context.Context, context.CancelFunc) {
+				ctx, cancel := context.WithCancel(context.Background())
+				time.AfterFunc(10*time.Millisecond, cancel)
+				return ctx, cancel
 			},
+			0,
+			"let f = fn(n) { f(n + 1) }; f(0)",
+			"evaluation cancelled",
+			context.Canceled,
+		},
+		{
+			"cancelled before starting",
+			func() (context.Context, context.CancelFunc) { return cancelled, func() {} },
+			0,
+			"1 + 2",
+			"evaluation cancelled",
+			context.Canceled,
+		},
+		{
+			"step budget exhausted",
+			func() (context.Context, context.CancelFunc) { return context.WithCancel(context.Background()) },
+			1000,
+			"let x = 0; while (true) { x += 1 }",
+			"step budget of 1000 exhausted",
+			ErrStepBudgetExhausted,
+		},
+		{
+			"step budget exhausted in recursion",
+			func() (context.Context, context.CancelFunc) { return context.WithCancel(context.Background()) },
+			1000,
+			"let f = fn(n) { f(n + 1) }; f(0)",
+			"step budget of 1000 exhausted",
+			ErrStepBudgetExhausted,
+		},
+		{
+			"ordinary errors have no cause",
+			func() (context.Context, context.CancelFunc) { return context.WithCancel(context.Background()) },
+			1000,
+			"1 + true",
+			"type mismatch: INTEGER + BOOLEAN",
+			nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := tt.ctx()
+			defer cancel()
+
+			ev := New()
+			ev.MaxSteps = tt.maxSteps
+			program := parse(t, tt.input)
+			evaluated := ev.EvalContext(ctx, program, object.NewEnvironment())
+
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Fatalf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+			}
+			if errObj.Message != tt.expectedMessage {
+				t.Errorf("wrong error message. want=%q, got=%q", tt.expectedMessage, errObj.Message)
+			}
+			if errObj.Cause != tt.expectedCause {
+				t.Errorf("wrong cause. want=%v, got=%v", tt.expectedCause, errObj.Cause)
+			}
+		})
+	}
+
+	t.Run("within budget", func(t *testing.T) {
+		ev := New()
+		ev.MaxSteps = 1000
+		program := parse(t, "let f = fn(n) { n * 2 }; f(21)")
+		testIntegerObject(t, ev.EvalContext(context.Background(), program, object.NewEnvironment()), 42)
+	})
+
+	t.Run("nested calls keep the budget", func(t *testing.T) {
+		ev := New()
+		ev.MaxSteps = 200
+		env := object.NewEnvironment()
+		env.Set("host", &object.Builtin{Name: "host", Fn: func(args ...object.Object) object.Object {
+			return ev.CallContext(context.Background(), args[0])
+		}})
+
+		program := parse(t, "let i = 0; while (i < 1000) { host(fn() { 1 }); i += 1 }")
+		evaluated := ev.EvalContext(context.Background(), program, env)
+
+		if errObj, ok := evaluated.(*object.Error); !ok || errObj.Cause != ErrStepBudgetExhausted {
+			t.Errorf("step budget not exhausted. got=%T(%+v)", evaluated, evaluated)
+		}
+	})
+
+	t.Run("nested calls keep the context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		ev := New()
+		env := object.NewEnvironment()
+		env.Set("host", &object.Builtin{Name: "host", Fn: func(args ...object.Object) object.Object {
+			result := ev.CallContext(context.Background(), args[0])
+			cancel()
+			return result
+		}})
+
+		program := parse(t, "while (true) { host(fn() { 1 }) }")
+		evaluated := ev.EvalContext(ctx, program, env)
+
+		if errObj, ok := evaluated.(*object.Error); !ok || errObj.Cause != context.Canceled {
+			t.Errorf("evaluation not cancelled. got=%T(%+v)", evaluated, evaluated)
+		}
+	})
+
+	t.Run("budget starts over for each evaluation", func(t *testing.T) {
+		ev := New()
+		ev.MaxSteps = 100
+		env := object.NewEnvironment()
+		for i := 0; i < 10; i++ {
+			program := parse(t, "let x = [1, 2, 3]; len(x)")
+			testIntegerObject(t, ev.EvalContext(context.Background(), program, env), 3)
+		}
+	})
+}
+
+func TestMemoryLimit(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"growing string", `let s = "ab"; while (true) { s = s + s }`},
+		{"growing array", "let a = []; while (true) { a = push(a, 1) }"},
+		{"growing hash", "let h = {}; let i = 0; while (true) { h[i] = i; i += 1 }"},
+		{"growing closures", "let f = fn() { 1 }; while (true) { let g = f; f = fn() { g() } }"},
+		{"deep recursion", "let f = fn(n) { 1 + f(n + 1) }; f(0)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ev := New()
+			ev.MaxDepth = 1 << 20
+			ev.MaxMemory = 1 << 16 // push copies its array, so growing one is quadratic
+			program := parse(t, tt.input)
+			evaluated := ev.EvalContext(context.Background(), program, object.NewEnvironment())
+
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Fatalf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+			}
+			if errObj.Message != "memory limit exceeded" {
+				t.Errorf("wrong error message. got=%q", errObj.Message)
+			}
+			if errObj.Cause != ErrMemoryLimitExceeded {
+				t.Errorf("wrong cause. got=%v", errObj.Cause)
+			}
+			if ev.PeakMemory() <= ev.MaxMemory {
+				t.Errorf("peak memory is within the limit. got=%d", ev.PeakMemory())
+			}
+		})
+	}
+
+	t.Run("values being computed", func(t *testing.T) {
+		// each call holds a new string of 1 MB as an argument until the calls under it return
+		input := `
+let s = "x";
+let i = 0;
+while (i < 20) { s = s + s; i += 1 }
+let k = fn(a, b) { 0 };
+let h = fn(n) { if (n == 0) { 0 } else { k(s + "y", h(n - 1)) } };
+h(500)`
+
+		ev := New()
+		ev.MaxMemory = 10 << 20
+		program := parse(t, input)
+		evaluated := ev.EvalContext(context.Background(), program, object.NewEnvironment())
+
+		if errObj, ok := evaluated.(*object.Error); !ok || errObj.Cause != ErrMemoryLimitExceeded {
+			t.Errorf("memory limit not exceeded. got=%T(%+v)", evaluated, evaluated)
+		}
+	})
+}
+
+func TestMemoryIsReleased(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected int64
+	}{
+		{"while loop", "let i = 0; while (i < 100000) { i = i + 1 } i", 100000},
+		{"for loop", `let n = 0; for (x in push([], 1)) { for (c in "abcdefghij") { n += 1 } } n`, 10},
+		{"string built and dropped", `let i = 0; while (i < 10000) { let s = "abc" + "def"; i += 1 } i`, 10000},
+		{"tail recursion", "let f = fn(n) { if (n == 0) { 0 } else { f(n - 1) } }; f(100000)", 0},
+		{"calls", "let f = fn(n) { [n, n] }; let i = 0; while (i < 100000) { f(i); i += 1 } i", 100000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ev := New()
+			ev.MaxMemory = 1 << 20
+			program := parse(t, tt.input)
+			evaluated := ev.EvalContext(context.Background(), program, object.NewEnvironment())
+
+			testIntegerObject(t, evaluated, tt.expected)
+			if ev.PeakMemory() > ev.MaxMemory {
+				t.Errorf("peak memory exceeds the limit. got=%d", ev.PeakMemory())
+			}
+		})
+	}
+
+	t.Run("what is still reachable is kept", func(t *testing.T) {
+		ev := New()
+		ev.MaxMemory = 1 << 16
+		program := parse(t, "let a = []; let i = 0; while (i < 100000) { a = push(a, i); i += 1 }")
+		evaluated := ev.EvalContext(context.Background(), program, object.NewEnvironment())
+
+		if errObj, ok := evaluated.(*object.Error); !ok || errObj.Cause != ErrMemoryLimitExceeded {
+			t.Errorf("memory limit not exceeded. got=%T(%+v)", evaluated, evaluated)
+		}
+	})
+}
+
+func TestPeakMemory(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected int64
+	}{
+		{"integer", "5", scalarSize},
+		{"string", `"abc"`, stringSize + 3},
+		{"let", "let x = 5;", scalarSize + bindingSize},
+		{"array", "[1, 2]", 2*scalarSize + arraySize + 2*elementSize},
+		{"hash", `{"a": 1}`, stringSize + 1 + scalarSize + hashSize + pairSize},
+		{"arithmetic", "1 + 2", 3 * scalarSize},
+		{"comparison", "1 < 2", 2 * scalarSize},
+		{"identifier", "let x = 5; x; x", scalarSize + bindingSize},
+		{"call", "fn(a) { a }(1)", functionSize + scalarSize + environmentSize + bindingSize},
+		{"builtin returning an element", `let a = ["hello"]; first(a)`, stringSize + 5 + arraySize + elementSize + bindingSize},
+		{"builtin returning a new object", `len("hello")`, stringSize + 5 + scalarSize},
+		{"new hash key", "let h = {}; h[1] = 2; h[1] = 3", hashSize + bindingSize + 4*scalarSize + pairSize},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ev := New()
+			program := parse(t, tt.input)
+			evaluated := ev.EvalContext(context.Background(), program, object.NewEnvironment())
+			if isError(evaluated) {
+				t.Fatalf("evaluation failed: %s", evaluated.Inspect())
+			}
+
+			if ev.PeakMemory() != tt.expected {
+				t.Errorf("wrong peak memory. want=%d, got=%d", tt.expected, ev.PeakMemory())
+			}
+		})
+	}
+
+	t.Run("starts over for each evaluation", func(t *testing.T) {
+		ev := New()
+		env := object.NewEnvironment()
+		for i := 0; i < 3; i++ {
+			ev.EvalContext(context.Background(), parse(t, `"abc"`), env)
 		}
+		if ev.PeakMemory() != stringSize+3 {
+			t.Errorf("wrong peak memory. want=%d, got=%d", stringSize+3, ev.PeakMemory())
+		}
+	})
+}
+
+func TestCheckedArithmetic(t *testing.T) {
+	tests := []struct {
+		name            string
+		input           string
+		expected        int64
+		expectedMessage string
+	}{
+		{"addition in range", "9223372036854775806 + 1", 9223372036854775807, ""},
+		{"addition overflow", "9223372036854775807 + 1", 0, "integer overflow: 9223372036854775807 + 1"},
+		{"subtraction in range", "-9223372036854775807 - 1", -9223372036854775807 - 1, ""},
+		{"subtraction overflow", "-9223372036854775807 - 2", 0, "integer overflow: -9223372036854775807 - 2"},
+		{"multiplication in range", "-3037000499 * 3037000499", -3037000499 * 3037000499, ""},
+		{"multiplication overflow", "4294967296 * 4294967296", 0, "integer overflow: 4294967296 * 4294967296"},
+		{"multiplication by minus one", "(-9223372036854775807 - 1) * -1", 0, "integer overflow: -9223372036854775808 * -1"},
+		{"division overflow", "(-9223372036854775807 - 1) / -1", 0, "integer overflow: -9223372036854775808 / -1"},
+		{"negation overflow", "-(-9223372036854775807 - 1)", 0, "integer overflow: -(-9223372036854775808)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			program := parse(t, tt.input)
+
+			ev := New()
+			ev.CheckedArithmetic = true
+			evaluated := ev.Eval(program, object.NewEnvironment())
+
+			if tt.expectedMessage == "" {
+				testIntegerObject(t, evaluated, tt.expected)
+				return
+			}
+
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Fatalf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+			}
+			if errObj.Message != tt.expectedMessage {
+				t.Errorf("wrong error message. want=%q, got=%q", tt.expectedMessage, errObj.Message)
+			}
+		})
+	}
+
+	t.Run("big integers are unaffected", func(t *testing.T) {
+		program := parse(t, "9223372036854775808 * 2")
+
+		ev := New()
+		ev.CheckedArithmetic = true
+		testBigIntObject(t, ev.Eval(program, object.NewEnvironment()), "18446744073709551616")
+	})
+}
+
+func TestFloats(t *testing.T) {
+	t.Run("arithmetic", func(t *testing.T) {
+		tests := []struct {
+			input    string
+			expected float64
+		}{
+			{"3.14", 3.14},
+			{"1e-9", 1e-9},
+			{"2.5E+3", 2500},
+			{"-1.5", -1.5},
+			{"0.1 + 0.2", 0.30000000000000004},
+			{"1.5 * 2", 3},
+			{"1 + 0.5", 1.5},
+			{"7 / 2.0", 3.5},
+			{"5.5 % 2", 1.5},
+			{"99999999999999999999 * 1.0", 1e20},
+			{"-(2.0 - 3)", 1},
+		}
+
 		for _, tt := range tests {
-			t.Run(tt.name, func(t *testing.T) {
-				evaluated := testEval(tt.input)
+			t.Run(tt.input, func(t *testing.T) {
+				testFloatObject(t, testEval(t, tt.input), tt.expected)
+			})
+		}
+	})
 
-				errObj, ok := evaluated.(*object.Error)
-				if !ok {
-					t.Errorf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+	t.Run("comparison", func(t *testing.T) {
+		tests := []struct {
+			input    string
+			expected bool
+		}{
+			{"1.5 < 2", true},
+			{"2 > 1.5", true},
+			{"1.0 == 1", true},
+			{"1.0 != 1", false},
+			{"0.1 + 0.2 == 0.3", false},
+			{"2.5 >= 2.5", true},
+			{"0.0 / 0 == 0.0 / 0", false},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.input, func(t *testing.T) {
+				testBooleanObject(t, testEval(t, tt.input), tt.expected)
+			})
+		}
+	})
+
+	t.Run("division by zero", func(t *testing.T) {
+		tests := []struct {
+			input    string
+			expected string
+		}{
+			{"1.0 / 0", "+Inf"},
+			{"-1 / 0.0", "-Inf"},
+			{"0.0 / 0", "NaN"},
+			{"1.5 % 0", "NaN"},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.input, func(t *testing.T) {
+				evaluated := testEval(t, tt.input)
+				if _, ok := evaluated.(*object.Float); !ok {
+					t.Fatalf("object is not Float. got=%T (%+v)", evaluated, evaluated)
+				}
+				if evaluated.Inspect() != tt.expected {
+					t.Errorf("wrong value. want=%s, got=%s", tt.expected, evaluated.Inspect())
 				}
-				if errObj.Message != tt.expectedMessage {
-					t.Errorf("wrong error message. want=%q, got=%q", tt.expectedMessage, errObj.Message)
+			})
+		}
+	})
+
+	t.Run("inspect", func(t *testing.T) {
+		tests := []struct {
+			input    string
+			expected string
+		}{
+			{"3.0", "3.0"},
+			{"3.25", "3.25"},
+			{"1e-9", "1e-09"},
+			{"1e21", "1e+21"},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.input, func(t *testing.T) {
+				if inspected := testEval(t, tt.input).Inspect(); inspected != tt.expected {
+					t.Errorf("wrong Inspect. want=%s, got=%s", tt.expected, inspected)
 				}
 			})
 		}
 	})
 }
 
-func testEval(input string) object.Object {
-	l := lexer.New(input)
-	p := parser.New(l)
-	program := p.ParseProgram()
-	env := object.NewEnvironment()
+func TestBigIntegers(t *testing.T) {
+	t.Run("promotion", func(t *testing.T) {
+		tests := []struct {
+			input    string
+			expected string
+		}{
+			{"99999999999999999999", "99999999999999999999"},
+			{"-99999999999999999999", "-99999999999999999999"},
+			{"9223372036854775807 + 1", "9223372036854775808"},
+			{"-9223372036854775807 - 2", "-9223372036854775809"},
+			{"4294967296 * 4294967296", "18446744073709551616"},
+			{"-(-9223372036854775807 - 1)", "9223372036854775808"},
+			{"(-9223372036854775807 - 1) / -1", "9223372036854775808"},
+			{"99999999999999999999 * 10 + 9", "999999999999999999999"},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.input, func(t *testing.T) {
+				testBigIntObject(t, testEval(t, tt.input), tt.expected)
+			})
+		}
+	})
+
+	t.Run("demotion", func(t *testing.T) {
+		tests := []struct {
+			input    string
+			expected int64
+		}{
+			{"9223372036854775808 - 1", 9223372036854775807},
+			{"99999999999999999999 - 99999999999999999998", 1},
+			{"99999999999999999999 / 99999999999999999999", 1},
+			{"99999999999999999999 % 10", 9},
+			{"-9223372036854775808", -9223372036854775807 - 1},
+			{"let x = 9223372036854775807 + 10; x - 20", 9223372036854775797},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.input, func(t *testing.T) {
+				testIntegerObject(t, testEval(t, tt.input), tt.expected)
+			})
+		}
+	})
+
+	t.Run("comparison", func(t *testing.T) {
+		tests := []struct {
+			input    string
+			expected bool
+		}{
+			{"99999999999999999999 > 1", true},
+			{"1 < 99999999999999999999", true},
+			{"-99999999999999999999 < 1", true},
+			{"99999999999999999999 == 99999999999999999999", true},
+			{"99999999999999999999 != 99999999999999999998", true},
+			{"9223372036854775807 + 1 == 9223372036854775808", true},
+			{"9223372036854775808 - 1 == 9223372036854775807", true},
+			{"99999999999999999999 == 1", false},
+			{"99999999999999999999 >= 99999999999999999999", true},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.input, func(t *testing.T) {
+				testBooleanObject(t, testEval(t, tt.input), tt.expected)
+			})
+		}
+	})
+
+	t.Run("hash keys", func(t *testing.T) {
+		testIntegerObject(t, testEval(t, "{99999999999999999999: 1}[99999999999999999998 + 1]"), 1)
+	})
+
+	t.Run("division by zero", func(t *testing.T) {
+		evaluated := testEval(t, "99999999999999999999 / 0")
+
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Fatalf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+		}
+		if errObj.Message != "division by zero" {
+			t.Errorf("wrong error message. got=%q", errObj.Message)
+		}
+	})
+}
 
-	return Eval(program, env)
+func testEval(t *testing.T, input string) object.Object {
+	t.Helper()
+
+	return Eval(parse(t, input), object.NewEnvironment())
+}
+
+// parse parses input, failing the test if it does not parse.
+func parse(t *testing.T, input string) *ast.Program {
+	t.Helper()
+
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+	return program
 }
 
 func testNullObject(t *testing.T, obj object.Object) bool {
@@ -492,6 +1250,49 @@ func testIntegerObject(t *testing.T, obj object.Object, expected int64) bool {
 	return true
 }
 
+func testBigIntObject(t *testing.T, obj object.Object, expected string) bool {
+	result, ok := obj.(*object.BigInt)
+	if !ok {
+		t.Errorf("object is not BigInt. got=%T (%+v)", obj, obj)
+		return false
+	}
+
+	if result.Value.String() != expected {
+		t.Errorf("object has wrong value. got=%s, want=%s", result.Value, expected)
+		return false
+	}
+
+	return true
+}
+
+func testFloatObject(t *testing.T, obj object.Object, expected float64) bool {
+	result, ok := obj.(*object.Float)
+	if !ok {
+		t.Errorf("object is not Float. got=%T (%+v)", obj, obj)
+		return false
+	}
+
+	if result.Value != expected {
+		t.Errorf("object has wrong value. got=%g, want=%g", result.Value, expected)
+		return false
+	}
+
+	return true
+}
+
+func testStringObject(t *testing.T, obj object.Object, expected string) bool {
+	result, ok := obj.(*object.String)
+	if !ok {
+		t.Errorf("object is not String. got=%T (%+v)", obj, obj)
+		return false
+	}
+	if result.Value != expected {
+		t.Errorf("object has wrong value. want=%q, got=%q", expected, result.Value)
+		return false
+	}
+	return true
+}
+
 func testBooleanObject(t *testing.T, obj object.Object, expected bool) bool {
 	result, ok := obj.(*object.Boolean)
 	if !ok {
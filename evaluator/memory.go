@@ -0,0 +1,207 @@
+package evaluator
+
+import (
+	"errors"
+	"github.com/jacksonopp/monkey/object"
+)
+
+// ErrMemoryLimitExceeded is the Cause of the error returned when a program allocates
+// more than MaxMemory bytes.
+var ErrMemoryLimitExceeded = errors.New("memory limit exceeded")
+
+// The sizes, in bytes, that the evaluator charges for the objects it allocates. They are
+// estimates of the Go values behind each object on a 64-bit platform, and only count an
+// object itself: the elements of an array or the values in a hash or environment are
+// charged when they are allocated.
+const (
+	scalarSize      = 16 // an Integer or Float, or a BigInt before its digits
+	stringSize      = 16 // a String before its bytes
+	arraySize       = 24 // an Array before its elements
+	elementSize     = 16 // each element of an Array
+	hashSize        = 48 // a Hash before its pairs
+	pairSize        = 64 // each pair in a Hash, along with its key
+	functionSize    = 64 // a Function, which shares its body with the function literal
+	environmentSize = 48 // an Environment before its bindings
+	bindingSize     = 48 // each name bound in an Environment
+)
+
+// measureMinimum is how many bytes the evaluator lets a program allocate before it first
+// measures how much of that memory the program can still reach.
+const measureMinimum = 64 << 10
+
+// PeakMemory returns the most memory the program held during the last call to
+// EvalContext, in bytes, by the evaluator's accounting. Objects count from when they are
+// allocated until the evaluator next measures what the program can still reach, which it
+// does whenever the memory counted has doubled since the last measurement and before
+// reporting that MaxMemory is exceeded. The peak can therefore overstate what the program
+// held at any one time, but never understates it by the same accounting, so a MaxMemory
+// of at least the peak lets the same program run.
+func (e *Evaluator) PeakMemory() int64 {
+	return e.peak
+}
+
+// allocate charges for obj, which the evaluator has just created, returning obj or the
+// error that stops evaluation once MaxMemory is exceeded.
+func (e *Evaluator) allocate(obj object.Object) object.Object {
+	if err := e.charge(sizeOf(obj)); err != nil {
+		return err
+	}
+	return obj
+}
+
+// charge records that size more bytes have been allocated, measuring what the program
+// still holds when the memory counted is due to be checked.
+func (e *Evaluator) charge(size int64) *object.Error {
+	e.memory += size
+
+	if e.memory > e.nextMeasure || (e.MaxMemory > 0 && e.memory > e.MaxMemory) {
+		// what was just allocated is not reachable yet, so it is added to what is
+		e.memory = e.measure() + size
+		e.nextMeasure = 2*e.memory + measureMinimum
+	}
+
+	if e.memory > e.peak {
+		e.peak = e.memory
+	}
+
+	if e.MaxMemory > 0 && e.memory > e.MaxMemory {
+		err := object.NewError("memory limit exceeded")
+		err.Cause = ErrMemoryLimitExceeded
+		return err
+	}
+	return nil
+}
+
+// enterScope records that the program is running in env, so that what env can reach is
+// measured as held, until the matching call to exitScope.
+func (e *Evaluator) enterScope(env *object.Environment) {
+	e.scopes = append(e.scopes, env)
+}
+
+func (e *Evaluator) exitScope() {
+	e.scopes = e.scopes[:len(e.scopes)-1]
+}
+
+// hold records that the program holds objs while it computes something from them, such
+// as the operands of an infix expression or the arguments of a call, so that they are
+// measured as held until they are released.
+func (e *Evaluator) hold(objs ...object.Object) {
+	e.pending = append(e.pending, objs...)
+}
+
+// holdArguments holds the arguments of a call.
+func (e *Evaluator) holdArguments(args []object.Object, named []namedArgument) {
+	e.hold(args...)
+	for _, arg := range named {
+		e.hold(arg.value)
+	}
+}
+
+// release lets go of the values held since there were mark of them, which is usually
+// deferred as e.release(len(e.pending)) before holding any.
+func (e *Evaluator) release(mark int) {
+	e.pending = e.pending[:mark]
+}
+
+// chargeHolding charges size bytes while holding obj, for the allocations made just
+// before obj is bound to a name.
+func (e *Evaluator) chargeHolding(size int64, obj object.Object) *object.Error {
+	defer e.release(len(e.pending))
+	e.hold(obj)
+	return e.charge(size)
+}
+
+// measure returns the size of the objects and environments that the program can still
+// reach from the environments it is running in and from the values it holds.
+func (e *Evaluator) measure() int64 {
+	m := measurement{
+		objects:      map[object.Object]bool{},
+		environments: map[*object.Environment]bool{},
+	}
+	for _, env := range e.scopes {
+		m.environment(env)
+	}
+	for _, obj := range e.pending {
+		m.object(obj)
+	}
+	return m.size
+}
+
+// measurement adds up the size of everything reachable, counting each object once.
+type measurement struct {
+	size         int64
+	objects      map[object.Object]bool
+	environments map[*object.Environment]bool
+}
+
+func (m *measurement) environment(env *object.Environment) {
+	for ; env != nil && !m.environments[env]; env = env.Outer() {
+		m.environments[env] = true
+		m.size += environmentSize
+
+		env.Each(func(name string, val object.Object) {
+			m.size += bindingSize
+			m.object(val)
+		})
+	}
+}
+
+func (m *measurement) object(obj object.Object) {
+	if obj == nil || m.objects[obj] {
+		return
+	}
+	m.objects[obj] = true
+	m.size += sizeOf(obj)
+
+	switch obj := obj.(type) {
+	case *object.Array:
+		for _, element := range obj.Elements {
+			m.object(element)
+		}
+	case *object.Hash:
+		for _, pair := range obj.Pairs {
+			m.object(pair.Key)
+			m.object(pair.Value)
+		}
+	case *object.Function:
+		m.environment(obj.Env)
+	}
+}
+
+// sizeOf returns what the evaluator charges for obj. Singletons such as TRUE and NULL
+// are free, as are errors and the other values that only pass control around.
+func sizeOf(obj object.Object) int64 {
+	switch obj := obj.(type) {
+	case *object.Integer, *object.Float:
+		return scalarSize
+	case *object.BigInt:
+		return scalarSize + int64(len(obj.Value.Bits()))*8
+	case *object.String:
+		return stringSize + int64(len(obj.Value))
+	case *object.Array:
+		return arraySize + int64(len(obj.Elements))*elementSize
+	case *object.Hash:
+		return hashSize + int64(len(obj.Pairs))*pairSize
+	case *object.Function:
+		return functionSize
+	default:
+		return 0
+	}
+}
+
+// isArgument reports whether the result of a builtin is one of its arguments, or one of
+// the elements at the ends of an array argument as returned by first and last, rather than
+// a new object.
+func isArgument(result object.Object, args []object.Object) bool {
+	for _, arg := range args {
+		if result == arg {
+			return true
+		}
+		if array, ok := arg.(*object.Array); ok && len(array.Elements) > 0 {
+			if result == array.Elements[0] || result == array.Elements[len(array.Elements)-1] {
+				return true
+			}
+		}
+	}
+	return false
+}
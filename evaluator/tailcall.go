@@ -0,0 +1,26 @@
+package evaluator
+
+import (
+	"github.com/jacksonopp/monkey/object"
+	"github.com/jacksonopp/monkey/token"
+)
+
+const TAIL_CALL_OBJ = "TAIL_CALL"
+
+// tailCall is what a call in tail position evaluates to. Rather than calling the function
+// itself, which would nest another Go frame for every iteration of a recursive loop, the
+// call is handed back to callFunction, which makes it in place of the call that is ending.
+type tailCall struct {
+	function object.Object
+	args     []object.Object
+	named    []namedArgument
+	callSite token.Position
+}
+
+func (t tailCall) Type() object.ObjectType {
+	return TAIL_CALL_OBJ
+}
+
+func (t tailCall) Inspect() string {
+	return "tail call to " + t.function.Inspect()
+}
@@ -1,7 +1,11 @@
 package lexer
 
 import (
+	"fmt"
 	"github.com/jacksonopp/monkey/token"
+	"strconv"
+	"strings"
+	"unicode/utf8"
 )
 
 type Lexer struct {
@@ -9,57 +13,128 @@ type Lexer struct {
 	position     int  // the index current position being read
 	readPosition int  // the index of the next position to read
 	ch           byte // the value of the current position being read
+
+	filename string // the file the input came from, used in positions
+	line     int    // the line of the current position
+	column   int    // the column of the current position
+
+	errors    []string // problems found while reading tokens, such as unterminated strings
+	eofErrors int      // how many of errors were caused by the input ending too soon
+
+	keepComments bool // return comments as COMMENT tokens instead of skipping them
 }
 
 func New(input string) *Lexer {
-	l := &Lexer{input: input}
+	return NewFile("", input)
+}
+
+// NewFile creates a Lexer for input read from filename, so that token positions
+// name the file they came from.
+func NewFile(filename, input string) *Lexer {
+	l := &Lexer{input: input, filename: filename, line: 1}
 	l.readChar()
 	return l
 }
 
+// KeepComments makes the lexer return comments as COMMENT tokens rather than skipping
+// them like whitespace, for tools that need to preserve them.
+func (l *Lexer) KeepComments(keep bool) {
+	l.keepComments = keep
+}
+
 func (l *Lexer) NextToken() token.Token {
 	var tok token.Token
 
 	l.skipWhitespace()
 
+	pos := l.currentPos()
+
 	switch l.ch {
 	// Operators
 	case '=':
 		if l.peekChar() == '=' {
-			ch := l.ch
-			l.readChar()
-			literal := string(ch) + string(l.ch)
-			tok = token.Token{Type: token.EQ, Literal: literal}
+			tok = l.readTwoCharToken(token.EQ)
 		} else {
 			tok = newToken(token.ASSIGN, l.ch)
 		}
 	case '+':
-		tok = newToken(token.PLUS, l.ch)
+		if l.peekChar() == '=' {
+			tok = l.readTwoCharToken(token.PLUS_ASSIGN)
+		} else {
+			tok = newToken(token.PLUS, l.ch)
+		}
 	case '-':
-		tok = newToken(token.MINUS, l.ch)
+		if l.peekChar() == '=' {
+			tok = l.readTwoCharToken(token.MINUS_ASSIGN)
+		} else {
+			tok = newToken(token.MINUS, l.ch)
+		}
 	case '!':
 		if l.peekChar() == '=' {
-			ch := l.ch
-			l.readChar()
-			literal := string(ch) + string(l.ch)
-			tok = token.Token{Type: token.NEQ, Literal: literal}
+			tok = l.readTwoCharToken(token.NEQ)
 		} else {
 			tok = newToken(token.BANG, l.ch)
 		}
 	case '*':
-		tok = newToken(token.ASTERISK, l.ch)
+		if l.peekChar() == '=' {
+			tok = l.readTwoCharToken(token.ASTERISK_ASSIGN)
+		} else {
+			tok = newToken(token.ASTERISK, l.ch)
+		}
 	case '/':
-		tok = newToken(token.SLASH, l.ch)
+		if l.atComment() {
+			tok.Type = token.COMMENT
+			tok.Literal = l.readComment(pos)
+			tok.Pos = pos
+			return tok
+		}
+		if l.peekChar() == '=' {
+			tok = l.readTwoCharToken(token.SLASH_ASSIGN)
+		} else {
+			tok = newToken(token.SLASH, l.ch)
+		}
+	case '%':
+		tok = newToken(token.PERCENT, l.ch)
 	case '<':
-		tok = newToken(token.LT, l.ch)
+		if l.peekChar() == '=' {
+			tok = l.readTwoCharToken(token.LT_EQ)
+		} else {
+			tok = newToken(token.LT, l.ch)
+		}
 	case '>':
-		tok = newToken(token.GT, l.ch)
+		if l.peekChar() == '=' {
+			tok = l.readTwoCharToken(token.GT_EQ)
+		} else {
+			tok = newToken(token.GT, l.ch)
+		}
+	case '&':
+		if l.peekChar() == '&' {
+			tok = l.readTwoCharToken(token.AND)
+		} else {
+			tok = newToken(token.ILLEGAL, l.ch)
+		}
+	case '|':
+		if l.peekChar() == '|' {
+			tok = l.readTwoCharToken(token.OR)
+		} else {
+			tok = newToken(token.ILLEGAL, l.ch)
+		}
 
 	// DELIMITERS
 	case ',':
 		tok = newToken(token.COMMA, l.ch)
 	case ';':
 		tok = newToken(token.SEMICOLON, l.ch)
+	case ':':
+		tok = newToken(token.COLON, l.ch)
+	case '.':
+		if l.peekChar() == '.' && l.readPosition+1 < len(l.input) && l.input[l.readPosition+1] == '.' {
+			l.readChar()
+			l.readChar()
+			tok = token.Token{Type: token.ELLIPSIS, Literal: "..."}
+		} else {
+			tok = newToken(token.ILLEGAL, l.ch)
+		}
 	case '(':
 		tok = newToken(token.LPAREN, l.ch)
 	case ')':
@@ -68,18 +143,65 @@ func (l *Lexer) NextToken() token.Token {
 		tok = newToken(token.LBRACE, l.ch)
 	case '}':
 		tok = newToken(token.RBRACE, l.ch)
+	case '[':
+		tok = newToken(token.LBRACKET, l.ch)
+	case ']':
+		tok = newToken(token.RBRACKET, l.ch)
+
+	// LITERALS
+	case '"':
+		tok.Type = token.STRING
+		tok.Literal = l.readString(pos)
 	case 0:
 		tok.Type = token.EOF
 		tok.Literal = ""
 	default:
-		return l.handleIdentifier()
+		tok = l.handleIdentifier()
+		tok.Pos = pos
+		return tok
 	}
 
 	l.readChar()
 
+	tok.Pos = pos
 	return tok
 }
 
+// Errors returns the problems the lexer ran in to while reading tokens.
+func (l *Lexer) Errors() []string {
+	return l.errors
+}
+
+// Incomplete reports whether every problem the lexer ran in to was caused by the input
+// ending too soon, such as an unterminated string, so that more input could fix them.
+func (l *Lexer) Incomplete() bool {
+	return len(l.errors) > 0 && l.eofErrors == len(l.errors)
+}
+
+func (l *Lexer) addError(pos token.Position, format string, a ...interface{}) {
+	if l.ch == 0 {
+		l.eofErrors += 1
+	}
+	l.errors = append(l.errors, pos.String()+": "+fmt.Sprintf(format, a...))
+}
+
+// currentPos returns the position of the character currently being read.
+func (l *Lexer) currentPos() token.Position {
+	return token.Position{
+		Filename: l.filename,
+		Offset:   l.position,
+		Line:     l.line,
+		Column:   l.column,
+	}
+}
+
+// readTwoCharToken reads an operator made of the current and next characters, such as ==.
+func (l *Lexer) readTwoCharToken(tokenType token.TokenType) token.Token {
+	ch := l.ch
+	l.readChar()
+	return token.Token{Type: tokenType, Literal: string(ch) + string(l.ch)}
+}
+
 // peekChar returns the next character if it exists, but does not advance the current index.
 func (l *Lexer) peekChar() byte {
 	if l.readPosition >= len(l.input) {
@@ -88,10 +210,50 @@ func (l *Lexer) peekChar() byte {
 	return l.input[l.readPosition]
 }
 
+// skipWhitespace skips whitespace, and comments unless the lexer keeps them.
 func (l *Lexer) skipWhitespace() {
-	for l.ch == ' ' || l.ch == '\t' || l.ch == '\n' || l.ch == '\r' {
+	for {
+		switch {
+		case l.ch == ' ' || l.ch == '\t' || l.ch == '\n' || l.ch == '\r':
+			l.readChar()
+		case !l.keepComments && l.atComment():
+			l.readComment(l.currentPos())
+		default:
+			return
+		}
+	}
+}
+
+// atComment reports whether the lexer is at the start of a // or /* comment.
+func (l *Lexer) atComment() bool {
+	return l.ch == '/' && (l.peekChar() == '/' || l.peekChar() == '*')
+}
+
+// readComment reads a // comment up to the end of the line or a /* */ comment up to the
+// closing */, returning it with its delimiters. It leaves the lexer after the comment.
+func (l *Lexer) readComment(start token.Position) string {
+	pos := l.position
+
+	if l.peekChar() == '/' {
+		for l.ch != '\n' && l.ch != 0 {
+			l.readChar()
+		}
+		return l.input[pos:l.position]
+	}
+
+	l.readChar()
+	l.readChar()
+	for !(l.ch == '*' && l.peekChar() == '/') {
+		if l.ch == 0 {
+			l.addError(start, "unterminated block comment")
+			return l.input[pos:l.position]
+		}
 		l.readChar()
 	}
+	l.readChar()
+	l.readChar()
+
+	return l.input[pos:l.position]
 }
 
 func (l *Lexer) handleIdentifier() token.Token {
@@ -104,23 +266,134 @@ func (l *Lexer) handleIdentifier() token.Token {
 	}
 
 	if isDigit(l.ch) {
-		tok.Type = token.INT
-		tok.Literal = l.readNumber()
+		tok.Type, tok.Literal = l.readNumber()
 
 		return tok
 	}
 
-	return newToken(token.ILLEGAL, l.ch)
+	tok = newToken(token.ILLEGAL, l.ch)
+	l.readChar()
+
+	return tok
 }
 
-func (l *Lexer) readNumber() string {
+// readNumber reads an integer, or a float when the digits are followed by a fraction
+// such as .5 or an exponent such as e-9.
+func (l *Lexer) readNumber() (token.TokenType, string) {
 	pos := l.position
+	tokenType := token.TokenType(token.INT)
+
+	l.readDigits()
+
+	if l.ch == '.' && isDigit(l.peekChar()) {
+		tokenType = token.FLOAT
+		l.readChar()
+		l.readDigits()
+	}
+
+	if l.ch == 'e' || l.ch == 'E' {
+		next := l.peekChar()
+		if (next == '+' || next == '-') && l.readPosition+1 < len(l.input) {
+			next = l.input[l.readPosition+1]
+		}
 
+		if isDigit(next) {
+			tokenType = token.FLOAT
+			l.readChar()
+			if l.ch == '+' || l.ch == '-' {
+				l.readChar()
+			}
+			l.readDigits()
+		}
+	}
+
+	return tokenType, l.input[pos:l.position]
+}
+
+func (l *Lexer) readDigits() {
 	for isDigit(l.ch) {
 		l.readChar()
 	}
+}
+
+// readString reads a double-quoted string literal starting at start, decoding escape
+// sequences as it goes. It leaves the lexer on the closing quote.
+func (l *Lexer) readString(start token.Position) string {
+	var out strings.Builder
+
+	for {
+		l.readChar()
+
+		switch l.ch {
+		case '"':
+			return out.String()
+		case 0:
+			l.addError(start, "unterminated string")
+			return out.String()
+		case '\\':
+			l.readEscape(&out)
+		default:
+			out.WriteByte(l.ch)
+		}
+	}
+}
 
-	return l.input[pos:l.position]
+// readEscape decodes the escape sequence following a backslash in a string literal.
+func (l *Lexer) readEscape(out *strings.Builder) {
+	pos := l.currentPos()
+	l.readChar()
+
+	switch l.ch {
+	case 'n':
+		out.WriteByte('\n')
+	case 't':
+		out.WriteByte('\t')
+	case '"':
+		out.WriteByte('"')
+	case '\\':
+		out.WriteByte('\\')
+	case 'u':
+		l.readUnicodeEscape(pos, out)
+	case 0:
+		// readString reports the unterminated string
+	default:
+		l.addError(pos, "unknown escape sequence \\%c in string", l.ch)
+	}
+}
+
+// readUnicodeEscape decodes a \u{...} escape starting at pos, where the braces hold the
+// code point in hex.
+func (l *Lexer) readUnicodeEscape(pos token.Position, out *strings.Builder) {
+	if l.peekChar() == 0 {
+		// readString reports the unterminated string
+		return
+	}
+	if l.peekChar() != '{' {
+		l.addError(pos, "expected { after \\u in string")
+		return
+	}
+	l.readChar()
+
+	start := l.readPosition
+	for l.peekChar() != '}' {
+		if l.peekChar() == 0 {
+			return
+		}
+		if l.peekChar() == '"' {
+			l.addError(pos, "unterminated \\u{...} escape in string")
+			return
+		}
+		l.readChar()
+	}
+	digits := l.input[start:l.readPosition]
+	l.readChar()
+
+	code, err := strconv.ParseUint(digits, 16, 32)
+	if err != nil || !utf8.ValidRune(rune(code)) {
+		l.addError(pos, "invalid unicode escape \\u{%s} in string", digits)
+		return
+	}
+	out.WriteRune(rune(code))
 }
 
 // readIdentifier will parse an entire identifier
@@ -137,6 +410,11 @@ func (l *Lexer) readIdentifier() string {
 
 // readChar gives us the next character and advances the position of the input string
 func (l *Lexer) readChar() {
+	if l.ch == '\n' {
+		l.line += 1
+		l.column = 0
+	}
+
 	if l.readPosition >= len(l.input) {
 		l.ch = 0
 	} else {
@@ -145,4 +423,5 @@ func (l *Lexer) readChar() {
 
 	l.position = l.readPosition
 	l.readPosition += 1
+	l.column += 1
 }
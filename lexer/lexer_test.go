@@ -127,6 +127,288 @@ true false if else return
 	})
 }
 
+func TestOperators(t *testing.T) {
+	input := `a <= b >= c % d && e || f < g & h | i += -= *= /= ...j .. k`
+
+	tests := []testToken{
+		{token.IDENT, "a"},
+		{token.LT_EQ, "<="},
+		{token.IDENT, "b"},
+		{token.GT_EQ, ">="},
+		{token.IDENT, "c"},
+		{token.PERCENT, "%"},
+		{token.IDENT, "d"},
+		{token.AND, "&&"},
+		{token.IDENT, "e"},
+		{token.OR, "||"},
+		{token.IDENT, "f"},
+		{token.LT, "<"},
+		{token.IDENT, "g"},
+		{token.ILLEGAL, "&"},
+		{token.IDENT, "h"},
+		{token.ILLEGAL, "|"},
+		{token.IDENT, "i"},
+		{token.PLUS_ASSIGN, "+="},
+		{token.MINUS_ASSIGN, "-="},
+		{token.ASTERISK_ASSIGN, "*="},
+		{token.SLASH_ASSIGN, "/="},
+		{token.ELLIPSIS, "..."},
+		{token.IDENT, "j"},
+		{token.ILLEGAL, "."},
+		{token.ILLEGAL, "."},
+		{token.IDENT, "k"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+		assertTokenIsExpected(t, tok, tt, i)
+	}
+}
+
+func TestNumbers(t *testing.T) {
+	input := `5 3.14 1e-9 2.5E+3 7e 1.x 4.`
+
+	tests := []testToken{
+		{token.INT, "5"},
+		{token.FLOAT, "3.14"},
+		{token.FLOAT, "1e-9"},
+		{token.FLOAT, "2.5E+3"},
+		{token.INT, "7"},
+		{token.IDENT, "e"},
+		{token.INT, "1"},
+		{token.ILLEGAL, "."},
+		{token.IDENT, "x"},
+		{token.INT, "4"},
+		{token.ILLEGAL, "."},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+		assertTokenIsExpected(t, tok, tt, i)
+	}
+}
+
+func TestBrackets(t *testing.T) {
+	input := `[1, 2][0];`
+
+	tests := []testToken{
+		{token.LBRACKET, "["},
+		{token.INT, "1"},
+		{token.COMMA, ","},
+		{token.INT, "2"},
+		{token.RBRACKET, "]"},
+		{token.LBRACKET, "["},
+		{token.INT, "0"},
+		{token.RBRACKET, "]"},
+		{token.SEMICOLON, ";"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+		assertTokenIsExpected(t, tok, tt, i)
+	}
+}
+
+func TestHashes(t *testing.T) {
+	input := `{"foo": "bar"}`
+
+	tests := []testToken{
+		{token.LBRACE, "{"},
+		{token.STRING, "foo"},
+		{token.COLON, ":"},
+		{token.STRING, "bar"},
+		{token.RBRACE, "}"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+		assertTokenIsExpected(t, tok, tt, i)
+	}
+}
+
+func TestStrings(t *testing.T) {
+	t.Run("string literals", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			input    string
+			expected string
+		}{
+			{"plain", `"foobar"`, "foobar"},
+			{"with spaces", `"foo bar"`, "foo bar"},
+			{"empty", `""`, ""},
+			{"newline and tab", `"a\nb\tc"`, "a\nb\tc"},
+			{"escaped quote", `"say \"hi\""`, `say "hi"`},
+			{"escaped backslash", `"C:\\monkey"`, `C:\monkey`},
+			{"unicode escape", `"\u{48}\u{1F412}"`, "H\U0001F412"},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				l := New(tt.input)
+
+				assertTokenIsExpected(t, l.NextToken(), testToken{token.STRING, tt.expected}, 0)
+				assertTokenIsExpected(t, l.NextToken(), testToken{token.EOF, ""}, 1)
+
+				if len(l.Errors()) != 0 {
+					t.Errorf("lexer has errors: %v", l.Errors())
+				}
+			})
+		}
+	})
+
+	t.Run("string errors", func(t *testing.T) {
+		tests := []struct {
+			name          string
+			input         string
+			expectedError string
+		}{
+			{"unterminated", `"foobar`, "1:1: unterminated string"},
+			{"unterminated after escape", `"foobar\`, "1:1: unterminated string"},
+			{"unknown escape", `"\q"`, "1:2: unknown escape sequence \\q in string"},
+			{"unicode without braces", `"\u48"`, "1:2: expected { after \\u in string"},
+			{"invalid unicode", `"\u{zz}"`, "1:2: invalid unicode escape \\u{zz} in string"},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				l := New(tt.input)
+				for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+				}
+
+				errors := l.Errors()
+				if len(errors) == 0 {
+					t.Fatalf("expected lexer errors, got none")
+				}
+				if errors[0] != tt.expectedError {
+					t.Errorf("wrong error. want=%q, got=%q", tt.expectedError, errors[0])
+				}
+			})
+		}
+	})
+}
+
+func TestComments(t *testing.T) {
+	input := `// a line comment
+let x = 10 / 2; // trailing
+/* a block
+   comment */ x /**/ * 2
+/* not closed`
+
+	t.Run("skipped", func(t *testing.T) {
+		tests := []testToken{
+			{token.LET, "let"},
+			{token.IDENT, "x"},
+			{token.ASSIGN, "="},
+			{token.INT, "10"},
+			{token.SLASH, "/"},
+			{token.INT, "2"},
+			{token.SEMICOLON, ";"},
+			{token.IDENT, "x"},
+			{token.ASTERISK, "*"},
+			{token.INT, "2"},
+			{token.EOF, ""},
+		}
+
+		l := New(input)
+
+		for i, tt := range tests {
+			tok := l.NextToken()
+			assertTokenIsExpected(t, tok, tt, i)
+		}
+
+		expected := []string{"5:1: unterminated block comment"}
+		if len(l.Errors()) != 1 || l.Errors()[0] != expected[0] {
+			t.Errorf("wrong errors. want=%q, got=%q", expected, l.Errors())
+		}
+	})
+
+	t.Run("kept", func(t *testing.T) {
+		tests := []testToken{
+			{token.COMMENT, "// a line comment"},
+			{token.LET, "let"},
+			{token.IDENT, "x"},
+			{token.ASSIGN, "="},
+			{token.INT, "10"},
+			{token.SLASH, "/"},
+			{token.INT, "2"},
+			{token.SEMICOLON, ";"},
+			{token.COMMENT, "// trailing"},
+			{token.COMMENT, "/* a block\n   comment */"},
+			{token.IDENT, "x"},
+			{token.COMMENT, "/**/"},
+			{token.ASTERISK, "*"},
+			{token.INT, "2"},
+			{token.COMMENT, "/* not closed"},
+			{token.EOF, ""},
+		}
+
+		l := New(input)
+		l.KeepComments(true)
+
+		for i, tt := range tests {
+			tok := l.NextToken()
+			assertTokenIsExpected(t, tok, tt, i)
+		}
+	})
+}
+
+func TestPositions(t *testing.T) {
+	input := `let x = 5;
+  x + "two"
+	[1]`
+
+	tests := []struct {
+		expectedType   token.TokenType
+		expectedOffset int
+		expectedLine   int
+		expectedColumn int
+	}{
+		{token.LET, 0, 1, 1},
+		{token.IDENT, 4, 1, 5},
+		{token.ASSIGN, 6, 1, 7},
+		{token.INT, 8, 1, 9},
+		{token.SEMICOLON, 9, 1, 10},
+		{token.IDENT, 13, 2, 3},
+		{token.PLUS, 15, 2, 5},
+		{token.STRING, 17, 2, 7},
+		{token.LBRACKET, 24, 3, 2},
+		{token.INT, 25, 3, 3},
+		{token.RBRACKET, 26, 3, 4},
+		{token.EOF, 27, 3, 5},
+	}
+
+	l := NewFile("test.mk", input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("test[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+
+		expected := token.Position{
+			Filename: "test.mk",
+			Offset:   tt.expectedOffset,
+			Line:     tt.expectedLine,
+			Column:   tt.expectedColumn,
+		}
+		if tok.Pos != expected {
+			t.Errorf("test[%d] - position wrong. expected=%+v, got=%+v", i, expected, tok.Pos)
+		}
+	}
+}
+
 func assertTokenIsExpected(t *testing.T, tok token.Token, tt testToken, i int) {
 	if tok.Type != tt.expectedType {
 		t.Fatalf("test[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
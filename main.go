@@ -8,18 +8,34 @@ import (
 	user2 "os/user"
 )
 
-func main() {
-	user, err := user2.Current()
-	if err != nil {
-		panic(err)
-	}
+const usage = "usage: monkey [flags] run path/to/file.mk [args...]"
 
+func main() {
 	evalFlag := flag.Bool("eval", true, "start in eval mode")
 	astFlag := flag.Bool("ast", false, "start in ast mode")
 	tokenFlag := flag.Bool("token", false, "start in token mode")
+	engineFlag := flag.String("engine", repl.EngineEval, "the engine to execute programs with: eval or vm")
 
 	flag.Parse()
 
+	if *engineFlag != repl.EngineEval && *engineFlag != repl.EngineVM {
+		fmt.Fprintf(os.Stderr, "unknown engine %q, expected %s or %s\n", *engineFlag, repl.EngineEval, repl.EngineVM)
+		os.Exit(2)
+	}
+
+	if flag.Arg(0) == "run" {
+		if flag.NArg() < 2 {
+			fmt.Fprintln(os.Stderr, usage)
+			os.Exit(2)
+		}
+		os.Exit(runFile(flag.Arg(1), flag.Args()[2:], *engineFlag, os.Stderr))
+	}
+
+	user, err := user2.Current()
+	if err != nil {
+		panic(err)
+	}
+
 	flags := [3]*bool{evalFlag, astFlag, tokenFlag}
 
 	fmt.Println("astFlag", *astFlag, "tokenFlag", *tokenFlag)
@@ -30,8 +46,8 @@ func main() {
 	} else if *tokenFlag {
 		fmt.Printf("Starting in token mode\n")
 	} else {
-		fmt.Printf("Starting in eval mode\n")
+		fmt.Printf("Starting in eval mode with the %s engine\n", *engineFlag)
 	}
 	fmt.Printf("Feel free to type in commands\n")
-	repl.Start(os.Stdin, os.Stdout, flags)
+	repl.Start(os.Stdin, os.Stdout, flags, *engineFlag)
 }
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"github.com/jacksonopp/monkey/object"
+	"github.com/jacksonopp/monkey/repl"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunFile(t *testing.T) {
+	tests := []struct {
+		name   string
+		src    string
+		args   []string
+		code   int
+		stdout string
+		stderr string // the prefix stderr must start with, with FILE standing for the path
+	}{
+		{"success", "let x = 1 + 2;", nil, 0, "", ""},
+		{"args", `puts(len(args), args[0], args[1])`, []string{"a", "b"}, 0, "2\na\nb\n", ""},
+		{"no args", `puts(args)`, nil, 0, "[]\n", ""},
+		{"parse error", "let = 5;", nil, 1, "", "FILE:1:5: "},
+		{"top-level error", "let x = 1;\nx + true", nil, 1, "", "type mismatch: INTEGER + BOOLEAN"},
+		{"puts of a function ending in a statement", "let g = fn() { let x = 1 };\nputs(g())", nil, 0, "null\n", ""},
+		{"len of a function ending in a statement", "let g = fn() { let x = 1 };\nlen(g())", nil, 1, "", "argument to `len` not supported, got NULL"},
+	}
+
+	for _, engine := range []string{repl.EngineEval, repl.EngineVM} {
+		for _, tt := range tests {
+			t.Run(engine+"/"+tt.name, func(t *testing.T) {
+				path := filepath.Join(t.TempDir(), "script.mk")
+				if err := os.WriteFile(path, []byte(tt.src), 0o644); err != nil {
+					t.Fatal(err)
+				}
+
+				var stdout, stderr strings.Builder
+				object.SetOutput(&stdout)
+				t.Cleanup(func() { object.SetOutput(os.Stdout) })
+
+				code := runFile(path, tt.args, engine, &stderr)
+
+				if code != tt.code {
+					t.Errorf("wrong exit code. want=%d, got=%d (stderr=%q)", tt.code, code, stderr.String())
+				}
+				if stdout.String() != tt.stdout {
+					t.Errorf("wrong stdout. want=%q, got=%q", tt.stdout, stdout.String())
+				}
+				expected := strings.ReplaceAll(tt.stderr, "FILE", path)
+				if !strings.Contains(stderr.String(), expected) || (expected == "") != (stderr.String() == "") {
+					t.Errorf("wrong stderr. want %q, got=%q", expected, stderr.String())
+				}
+			})
+		}
+	}
+
+	t.Run("error position", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "script.mk")
+		if err := os.WriteFile(path, []byte("let x = 1;\nx + true"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		var stderr strings.Builder
+
+		runFile(path, nil, repl.EngineEval, &stderr)
+
+		expected := "ERROR: " + path + ":2:3: type mismatch: INTEGER + BOOLEAN\n"
+		if stderr.String() != expected {
+			t.Errorf("wrong stderr. want=%q, got=%q", expected, stderr.String())
+		}
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		var stderr strings.Builder
+
+		code := runFile(filepath.Join(t.TempDir(), "missing.mk"), nil, repl.EngineEval, &stderr)
+
+		if code != 1 {
+			t.Errorf("wrong exit code. got=%d", code)
+		}
+		if !strings.HasPrefix(stderr.String(), "monkey: open ") {
+			t.Errorf("wrong stderr. got=%q", stderr.String())
+		}
+	})
+}
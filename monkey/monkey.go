@@ -0,0 +1,190 @@
+// Package monkey embeds the Monkey programming language in Go programs.
+//
+//	interp := monkey.New()
+//	interp.Set("name", "world")
+//	result, err := interp.Eval(`"hello " + name`)
+package monkey
+
+import (
+	"context"
+	"fmt"
+	"github.com/jacksonopp/monkey/evaluator"
+	"github.com/jacksonopp/monkey/lexer"
+	"github.com/jacksonopp/monkey/object"
+	"github.com/jacksonopp/monkey/parser"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// Interpreter evaluates Monkey programs with the tree-walking evaluator. The globals one
+// program defines are visible to the programs evaluated after it. An Interpreter must not
+// be used by more than one goroutine at a time.
+type Interpreter struct {
+	// Stdout is where puts writes. It defaults to os.Stdout.
+	Stdout io.Writer
+
+	// Stderr is where programs that fail to parse or evaluate are reported, with the
+	// traceback of runtime errors, in addition to the error being returned. It defaults to
+	// io.Discard, leaving errors to the caller.
+	Stderr io.Writer
+
+	// Evaluator evaluates the programs, and can be configured to limit their depth, steps
+	// and memory.
+	Evaluator *evaluator.Evaluator
+
+	env *object.Environment
+}
+
+// New creates an Interpreter with no globals defined.
+func New() *Interpreter {
+	i := &Interpreter{
+		Stdout:    os.Stdout,
+		Stderr:    io.Discard,
+		Evaluator: evaluator.New(),
+		env:       object.NewEnvironment(),
+	}
+	i.env.Set("puts", &object.Builtin{Name: "puts", Fn: i.puts})
+	return i
+}
+
+// Error is returned when a Monkey program fails while it is evaluated.
+type Error struct {
+	Object *object.Error // the error the program evaluated to
+}
+
+func (e *Error) Error() string {
+	return strings.TrimPrefix(e.Object.Inspect(), "ERROR: ")
+}
+
+// Unwrap returns the Cause of the error, so that errors.Is can tell whether the host
+// stopped the program, such as with context.DeadlineExceeded.
+func (e *Error) Unwrap() error {
+	return e.Object.Cause
+}
+
+// ParseError is returned when the source of a program does not parse.
+type ParseError struct {
+	Errors []string // the problems the parser found, each starting with its position
+}
+
+func (e *ParseError) Error() string {
+	return strings.Join(e.Errors, "\n")
+}
+
+// Eval evaluates the program in src, returning what it evaluates to.
+func (i *Interpreter) Eval(src string) (object.Object, error) {
+	return i.EvalContext(context.Background(), src)
+}
+
+// EvalContext evaluates the program in src like Eval, stopping it when ctx is done.
+func (i *Interpreter) EvalContext(ctx context.Context, src string) (object.Object, error) {
+	return i.eval(ctx, "", src)
+}
+
+// EvalFile evaluates the program in the file at path, whose name is used in the
+// positions of errors.
+func (i *Interpreter) EvalFile(path string) (object.Object, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return i.eval(context.Background(), path, string(src))
+}
+
+func (i *Interpreter) eval(ctx context.Context, filename, src string) (result object.Object, err error) {
+	defer i.recoverPanic(&result, &err)
+
+	p := parser.New(lexer.NewFile(filename, src))
+	program := p.ParseProgram()
+
+	if len(p.Errors()) > 0 {
+		err := &ParseError{Errors: p.Errors()}
+		fmt.Fprintln(i.Stderr, err)
+		return nil, err
+	}
+
+	return i.result(i.Evaluator.EvalContext(ctx, program, i.env))
+}
+
+// Set defines the global name as value, replacing it if it is already defined. Go values
+// are converted with object.FromGo, so funcs can be set to be called as builtins.
+func (i *Interpreter) Set(name string, value interface{}) error {
+	var obj object.Object
+	var err error
+	if t := reflect.TypeOf(value); t != nil && t.Kind() == reflect.Func {
+		// funcs are named after the global rather than where they are declared
+		obj, err = object.NewGoBuiltin(name, value)
+	} else {
+		obj, err = object.FromGo(value)
+	}
+	if err != nil {
+		return err
+	}
+
+	i.env.Set(name, obj)
+	return nil
+}
+
+// Get returns the value of the global name.
+func (i *Interpreter) Get(name string) (object.Object, bool) {
+	return i.env.Get(name)
+}
+
+// Call calls the global function or builtin name with args, returning its result. Go
+// values among args are converted with object.FromGo.
+func (i *Interpreter) Call(name string, args ...interface{}) (object.Object, error) {
+	return i.CallContext(context.Background(), name, args...)
+}
+
+// CallContext calls the global function or builtin name like Call, stopping it when ctx
+// is done.
+func (i *Interpreter) CallContext(ctx context.Context, name string, args ...interface{}) (result object.Object, err error) {
+	defer i.recoverPanic(&result, &err)
+
+	objs := make([]object.Object, len(args))
+	for n, arg := range args {
+		obj, err := object.FromGo(arg)
+		if err != nil {
+			return nil, err
+		}
+		objs[n] = obj
+	}
+
+	fn, ok := i.env.Get(name)
+	if !ok {
+		fn, ok = object.LookupBuiltin(name)
+	}
+	if !ok {
+		return i.result(object.NewError("identifier not found: %s", name))
+	}
+
+	return i.result(i.Evaluator.CallContext(ctx, fn, objs...))
+}
+
+// result converts what a program evaluated to in to the results of Eval and Call.
+func (i *Interpreter) result(evaluated object.Object) (object.Object, error) {
+	if errObj, ok := evaluated.(*object.Error); ok {
+		fmt.Fprintln(i.Stderr, errObj.Traceback())
+		return nil, &Error{Object: errObj}
+	}
+	return evaluated, nil
+}
+
+// recoverPanic turns a panic while evaluating a program or calling a function in to the
+// error Eval and Call return, so that a bug in the interpreter or in a builtin does not
+// bring down the host. It is deferred with the results of the call it recovers.
+func (i *Interpreter) recoverPanic(result *object.Object, err *error) {
+	if r := recover(); r != nil {
+		*result, *err = i.result(object.NewError("interpreter panicked: %v", r))
+	}
+}
+
+// puts prints each argument on its own line to Stdout.
+func (i *Interpreter) puts(args ...object.Object) object.Object {
+	for _, arg := range args {
+		fmt.Fprintln(i.Stdout, arg.Inspect())
+	}
+	return object.NULL
+}
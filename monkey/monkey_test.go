@@ -0,0 +1,373 @@
+package monkey
+
+import (
+	"context"
+	"errors"
+	"github.com/jacksonopp/monkey/evaluator"
+	"github.com/jacksonopp/monkey/object"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEval(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"expression", "1 + 2", "3"},
+		{"statement", "let x = 5;", "null"},
+		{"function", "fn(x) { x }", "fn(x) {\nx\n}"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			interp, _, _ := newInterpreter()
+
+			result, err := interp.Eval(tt.input)
+			if err != nil {
+				t.Fatalf("Eval failed: %s", err)
+			}
+			if result.Inspect() != tt.expected {
+				t.Errorf("wrong result. want=%q, got=%q", tt.expected, result.Inspect())
+			}
+		})
+	}
+
+	t.Run("globals persist between programs", func(t *testing.T) {
+		interp, _, _ := newInterpreter()
+
+		if _, err := interp.Eval("let double = fn(x) { x * 2 };"); err != nil {
+			t.Fatalf("Eval failed: %s", err)
+		}
+		result, err := interp.Eval("double(21)")
+		if err != nil {
+			t.Fatalf("Eval failed: %s", err)
+		}
+		if result.Inspect() != "42" {
+			t.Errorf("wrong result. got=%q", result.Inspect())
+		}
+	})
+
+	t.Run("puts writes to stdout", func(t *testing.T) {
+		interp, stdout, _ := newInterpreter()
+
+		if _, err := interp.Eval(`puts("hello", 5)`); err != nil {
+			t.Fatalf("Eval failed: %s", err)
+		}
+		if stdout.String() != "hello\n5\n" {
+			t.Errorf("wrong output. got=%q", stdout.String())
+		}
+	})
+}
+
+func TestErrors(t *testing.T) {
+	t.Run("parse error", func(t *testing.T) {
+		interp, _, stderr := newInterpreter()
+
+		_, err := interp.Eval("let = 5;")
+		var parseErr *ParseError
+		if !errors.As(err, &parseErr) {
+			t.Fatalf("err is not *ParseError. got=%T(%v)", err, err)
+		}
+		if len(parseErr.Errors) == 0 || !strings.HasPrefix(parseErr.Errors[0], "1:5: ") {
+			t.Errorf("wrong parse errors. got=%q", parseErr.Errors)
+		}
+		if stderr.String() != err.Error()+"\n" {
+			t.Errorf("wrong stderr. got=%q", stderr.String())
+		}
+	})
+
+	t.Run("runtime error", func(t *testing.T) {
+		interp, _, stderr := newInterpreter()
+
+		_, err := interp.Eval("let f = fn() { 1 + true };\nf()")
+		var evalErr *Error
+		if !errors.As(err, &evalErr) {
+			t.Fatalf("err is not *Error. got=%T(%v)", err, err)
+		}
+		if err.Error() != "1:18: type mismatch: INTEGER + BOOLEAN" {
+			t.Errorf("wrong error. got=%q", err.Error())
+		}
+		if stderr.String() != evalErr.Object.Traceback()+"\n" {
+			t.Errorf("wrong stderr. got=%q", stderr.String())
+		}
+	})
+
+	t.Run("not reported by default", func(t *testing.T) {
+		if New().Stderr != io.Discard {
+			t.Errorf("Stderr does not default to io.Discard")
+		}
+	})
+
+	t.Run("panic", func(t *testing.T) {
+		interp, _, _ := newInterpreter()
+		boom := &object.Builtin{Name: "boom", Fn: func(args ...object.Object) object.Object { panic("boom") }}
+		if err := interp.Set("boom", boom); err != nil {
+			t.Fatalf("Set failed: %s", err)
+		}
+
+		tests := []struct {
+			name string
+			call func() (object.Object, error)
+		}{
+			{"eval", func() (object.Object, error) { return interp.Eval("boom()") }},
+			{"call", func() (object.Object, error) { return interp.Call("boom") }},
+		}
+
+		for _, tt := range tests {
+			_, err := tt.call()
+			if err == nil || err.Error() != "interpreter panicked: boom" {
+				t.Errorf("wrong error from %s. got=%v", tt.name, err)
+			}
+		}
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		interp, _, _ := newInterpreter()
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := interp.EvalContext(ctx, "while (true) {}")
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("err is not context.Canceled. got=%v", err)
+		}
+	})
+}
+
+func TestEvalFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "script.mk")
+	if err := os.WriteFile(path, []byte("let x = 2;\nx + true"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	interp, _, _ := newInterpreter()
+	_, err := interp.EvalFile(path)
+	if err == nil || err.Error() != path+":2:3: type mismatch: INTEGER + BOOLEAN" {
+		t.Errorf("wrong error. got=%v", err)
+	}
+
+	_, err = interp.EvalFile(filepath.Join(dir, "missing.mk"))
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("err is not os.ErrNotExist. got=%v", err)
+	}
+}
+
+func TestGlobals(t *testing.T) {
+	interp, _, _ := newInterpreter()
+
+	if err := interp.Set("greeting", "hello"); err != nil {
+		t.Fatalf("Set failed: %s", err)
+	}
+	result, err := interp.Eval(`let name = greeting + " world"; name`)
+	if err != nil {
+		t.Fatalf("Eval failed: %s", err)
+	}
+	if result.Inspect() != "hello world" {
+		t.Errorf("wrong result. got=%q", result.Inspect())
+	}
+
+	name, ok := interp.Get("name")
+	if !ok || name.Inspect() != "hello world" {
+		t.Errorf("wrong global. got=%v, %t", name, ok)
+	}
+
+	if _, ok := interp.Get("missing"); ok {
+		t.Errorf("missing global found")
+	}
+}
+
+func TestCall(t *testing.T) {
+	tests := []struct {
+		name     string
+		function string
+		args     []interface{}
+		expected string
+	}{
+		{"function", "add", []interface{}{1, 2}, "3"},
+		{"objects", "add", []interface{}{&object.Integer{Value: 1}, &object.Integer{Value: 2}}, "3"},
+		{"default parameter", "add", []interface{}{1}, "11"},
+		{"builtin", "len", []interface{}{"four"}, "4"},
+		{"wrong number of arguments", "add", []interface{}{}, "ERROR: wrong number of arguments to `add`: want=1..2, got=0"},
+		{"undefined", "subtract", nil, "ERROR: identifier not found: subtract"},
+		{"not a function", "x", nil, "ERROR: not a function: INTEGER"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			interp, _, _ := newInterpreter()
+			if _, err := interp.Eval("let add = fn(a, b = 10) { a + b }; let x = 5;"); err != nil {
+				t.Fatalf("Eval failed: %s", err)
+			}
+
+			result, err := interp.Call(tt.function, tt.args...)
+
+			var evalErr *Error
+			if errors.As(err, &evalErr) {
+				result = evalErr.Object
+			} else if err != nil {
+				t.Fatalf("Call failed: %s", err)
+			}
+			if result.Inspect() != tt.expected {
+				t.Errorf("wrong result. want=%q, got=%q", tt.expected, result.Inspect())
+			}
+		})
+	}
+
+	t.Run("traceback", func(t *testing.T) {
+		interp, _, stderr := newInterpreter()
+		if _, err := interp.Eval("let f = fn(x) { 1 + x / 0 };"); err != nil {
+			t.Fatalf("Eval failed: %s", err)
+		}
+
+		interp.Call("f", 1)
+
+		expected := "Traceback (most recent call last):\n" +
+			"  in <host>, called `f` with 1 argument\n" +
+			"ERROR: 1:23: division by zero\n"
+		if stderr.String() != expected {
+			t.Errorf("wrong stderr. want=%q, got=%q", expected, stderr.String())
+		}
+	})
+}
+
+func TestReentrantCalls(t *testing.T) {
+	interp, _, _ := newInterpreter()
+	interp.Evaluator.MaxSteps = 200
+	interp.Set("host", func() (object.Object, error) {
+		return interp.Call("double", 2)
+	})
+	if _, err := interp.Eval("let double = fn(x) { x * 2 };"); err != nil {
+		t.Fatalf("Eval failed: %s", err)
+	}
+
+	_, err := interp.Eval("let i = 0; while (i < 1000) { host(); i += 1 }")
+	if !errors.Is(err, evaluator.ErrStepBudgetExhausted) {
+		t.Errorf("err is not evaluator.ErrStepBudgetExhausted. got=%v", err)
+	}
+}
+
+func TestGoValues(t *testing.T) {
+	type user struct {
+		Name  string `monkey:"name"`
+		Admin bool   `monkey:"admin"`
+	}
+
+	tests := []struct {
+		name     string
+		value    interface{}
+		input    string
+		expected string
+	}{
+		{"struct", user{Name: "ann", Admin: true}, `if (x["admin"]) { x["name"] }`, "ann"},
+		{"slice", []int{1, 2, 3}, "len(x)", "3"},
+		{"func", strings.Repeat, `x("ab", 3)`, "ababab"},
+		{"func named after the global", strings.Repeat, `x("ab")`, "ERROR: 1:2: wrong number of arguments to `x`: want=2, got=1"},
+		{"func error", func() error { return errors.New("failed") }, "x()", "ERROR: 1:2: failed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			interp, _, _ := newInterpreter()
+			if err := interp.Set("x", tt.value); err != nil {
+				t.Fatalf("Set failed: %s", err)
+			}
+
+			result, err := interp.Eval(tt.input)
+
+			var evalErr *Error
+			if errors.As(err, &evalErr) {
+				result = evalErr.Object
+			} else if err != nil {
+				t.Fatalf("Eval failed: %s", err)
+			}
+			if result.Inspect() != tt.expected {
+				t.Errorf("wrong result. want=%q, got=%q", tt.expected, result.Inspect())
+			}
+		})
+	}
+
+	t.Run("func error is not a cause", func(t *testing.T) {
+		interp, _, _ := newInterpreter()
+		if err := interp.Set("x", func() error { return context.Canceled }); err != nil {
+			t.Fatalf("Set failed: %s", err)
+		}
+
+		_, err := interp.Eval("x()")
+
+		var evalErr *Error
+		if !errors.As(err, &evalErr) {
+			t.Fatalf("wrong error. got=%T (%v)", err, err)
+		}
+		if errors.Is(err, context.Canceled) {
+			t.Errorf("error from a Go function taken for the host stopping the program")
+		}
+		if evalErr.Object.GoErr != context.Canceled {
+			t.Errorf("wrong GoErr. got=%v", evalErr.Object.GoErr)
+		}
+	})
+
+	t.Run("unsupported", func(t *testing.T) {
+		interp, _, _ := newInterpreter()
+		if err := interp.Set("x", make(chan int)); err == nil {
+			t.Errorf("Set did not fail")
+		}
+	})
+}
+
+func TestFunctionsEndingInAStatement(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+		output   string
+	}{
+		{"puts", "puts(g())", "null", "null\n"},
+		{"len", "len(g())", "ERROR: 1:4: argument to `len` not supported, got NULL", ""},
+		{"go func", "double(g())", "ERROR: 1:7: argument 1 to `double` must be int, got NULL", ""},
+		{"hash key", "{g(): 1}", "ERROR: 1:1: unusable as hash key: NULL", ""},
+		{"infix", "g() + 1", "ERROR: 1:5: type mismatch: NULL + INTEGER", ""},
+		{"empty body", "fn() {}()", "null", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			interp, stdout, _ := newInterpreter()
+			if err := interp.Set("double", func(n int) int { return 2 * n }); err != nil {
+				t.Fatalf("Set failed: %s", err)
+			}
+			if _, err := interp.Eval("let g = fn() { let x = 1 };"); err != nil {
+				t.Fatalf("Eval failed: %s", err)
+			}
+
+			result, err := interp.Eval(tt.input)
+
+			var evalErr *Error
+			if errors.As(err, &evalErr) {
+				result = evalErr.Object
+			} else if err != nil {
+				t.Fatalf("Eval failed: %s", err)
+			}
+			if result.Inspect() != tt.expected {
+				t.Errorf("wrong result. want=%q, got=%q", tt.expected, result.Inspect())
+			}
+			if stdout.String() != tt.output {
+				t.Errorf("wrong output. want=%q, got=%q", tt.output, stdout.String())
+			}
+		})
+	}
+}
+
+func newInterpreter() (*Interpreter, *strings.Builder, *strings.Builder) {
+	var stdout, stderr strings.Builder
+
+	interp := New()
+	interp.Stdout = &stdout
+	interp.Stderr = &stderr
+
+	return interp, &stdout, &stderr
+}
@@ -0,0 +1,55 @@
+package object
+
+import (
+	"bytes"
+	"strings"
+)
+
+type Array struct {
+	Elements []Object
+}
+
+func (a Array) Type() ObjectType {
+	return ARRAY_OBJ
+}
+
+func (a Array) Inspect() string {
+	return a.inspect(visiting{})
+}
+
+// inspect prints the array, printing an array it is already inside of, which assigning
+// to an index can make it contain, as [...].
+func (a Array) inspect(seen visiting) string {
+	if len(a.Elements) > 0 {
+		// the array is a copy, so it is known by its elements
+		if !seen.enter(&a.Elements[0]) {
+			return "[...]"
+		}
+		defer seen.leave(&a.Elements[0])
+	}
+
+	var out bytes.Buffer
+
+	elements := []string{}
+	for _, e := range a.Elements {
+		elements = append(elements, inspect(e, seen))
+	}
+
+	out.WriteString("[")
+	out.WriteString(strings.Join(elements, ", "))
+	out.WriteString("]")
+
+	return out.String()
+}
+
+// inspect prints obj, passing seen on to the arrays and hashes it is made of.
+func inspect(obj Object, seen visiting) string {
+	switch obj := obj.(type) {
+	case *Array:
+		return obj.inspect(seen)
+	case *Hash:
+		return obj.inspect(seen)
+	default:
+		return obj.Inspect()
+	}
+}
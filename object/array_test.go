@@ -0,0 +1,33 @@
+package object
+
+import "testing"
+
+func TestInspectContainsItself(t *testing.T) {
+	array := &Array{Elements: []Object{&Integer{Value: 1}, NULL}}
+	array.Elements[1] = array
+
+	hash := NewHash()
+	hash.Set(&String{Value: "self"}, hash)
+	hash.Set(&String{Value: "array"}, array)
+
+	shared := &Array{Elements: []Object{&Integer{Value: 2}}}
+
+	tests := []struct {
+		name     string
+		obj      Object
+		expected string
+	}{
+		{"array", array, "[1, [...]]"},
+		{"hash", hash, "{self: {...}, array: [1, [...]]}"},
+		{"array in itself twice", &Array{Elements: []Object{array, array}}, "[[1, [...]], [1, [...]]]"},
+		{"shared array", &Array{Elements: []Object{shared, shared}}, "[[2], [2]]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.obj.Inspect() != tt.expected {
+				t.Errorf("wrong Inspect. want=%q, got=%q", tt.expected, tt.obj.Inspect())
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package object
+
+import (
+	"hash/fnv"
+	"math"
+	"math/big"
+)
+
+// BigInt is an integer that does not fit in an Integer. Arithmetic produces a BigInt only
+// when the result is out of the int64 range, so the two never hold the same value.
+type BigInt struct {
+	Value *big.Int
+}
+
+func (b BigInt) Type() ObjectType {
+	return BIGINT_OBJ
+}
+
+func (b BigInt) Inspect() string {
+	return b.Value.String()
+}
+
+func (b BigInt) HashKey() HashKey {
+	h := fnv.New64a()
+	h.Write([]byte(b.Value.String()))
+
+	return HashKey{Type: b.Type(), Value: h.Sum64()}
+}
+
+// NewInteger returns value as an Integer when it fits in an int64, or as a BigInt when
+// it does not.
+func NewInteger(value *big.Int) Object {
+	if value.IsInt64() {
+		return &Integer{Value: value.Int64()}
+	}
+	return &BigInt{Value: value}
+}
+
+// BigValue returns the value of an Integer or BigInt as a big.Int. It reports false for
+// any other object.
+func BigValue(obj Object) (*big.Int, bool) {
+	switch obj := obj.(type) {
+	case *Integer:
+		return big.NewInt(obj.Value), true
+	case *BigInt:
+		return obj.Value, true
+	default:
+		return nil, false
+	}
+}
+
+// IsInteger reports whether obj is an Integer or a BigInt.
+func IsInteger(obj Object) bool {
+	t := obj.Type()
+	return t == INTEGER_OBJ || t == BIGINT_OBJ
+}
+
+// IntegerOverflows reports whether applying the arithmetic operator to left and right
+// overflows an int64.
+func IntegerOverflows(operator string, left, right int64) bool {
+	switch operator {
+	case "+":
+		result := left + right
+		// the sum overflowed when both operands have a different sign to it
+		return (left^result)&(right^result) < 0
+	case "-":
+		result := left - right
+		return (left^right)&(left^result) < 0
+	case "*":
+		if left == 0 || right == 0 {
+			return false
+		}
+		result := left * right
+		return result/right != left || (left == -1 && right == math.MinInt64) || (right == -1 && left == math.MinInt64)
+	case "/":
+		return left == math.MinInt64 && right == -1
+	default:
+		return false
+	}
+}
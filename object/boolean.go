@@ -2,6 +2,12 @@ package object
 
 import "fmt"
 
+// TRUE and FALSE are the only Boolean values; they can be compared by identity.
+var (
+	TRUE  = &Boolean{Value: true}
+	FALSE = &Boolean{Value: false}
+)
+
 type Boolean struct {
 	Value bool
 }
@@ -0,0 +1,15 @@
+package object
+
+// BREAK is returned by a break statement and passed up through blocks until it reaches
+// the loop it stops.
+var BREAK = &Break{}
+
+type Break struct{}
+
+func (b Break) Type() ObjectType {
+	return BREAK_OBJ
+}
+
+func (b Break) Inspect() string {
+	return "break"
+}
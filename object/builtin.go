@@ -0,0 +1,26 @@
+package object
+
+// BuiltinFunction is a function implemented in Go that can be called from Monkey code.
+type BuiltinFunction func(args ...Object) Object
+
+type Builtin struct {
+	Name string // the name the builtin is registered under
+	Fn   BuiltinFunction
+}
+
+// CheckArity returns an error unless the builtin called name was given exactly want
+// arguments, reporting it the same way as calls to Monkey functions.
+func CheckArity(name string, args []Object, want int) *Error {
+	if len(args) != want {
+		return NewArityError(name, want, len(args))
+	}
+	return nil
+}
+
+func (b Builtin) Type() ObjectType {
+	return BUILTIN_OBJ
+}
+
+func (b Builtin) Inspect() string {
+	return "builtin function " + b.Name
+}
@@ -0,0 +1,248 @@
+package object
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+var (
+	// builtins holds the functions identifiers fall back to when they are not bound in the environment.
+	builtins = map[string]*Builtin{}
+
+	// output is where puts writes.
+	output io.Writer = os.Stdout
+
+	// mu guards builtins and output.
+	mu sync.RWMutex
+)
+
+func init() {
+	RegisterBuiltin("len", builtinLen)
+	RegisterBuiltin("first", builtinFirst)
+	RegisterBuiltin("last", builtinLast)
+	RegisterBuiltin("rest", builtinRest)
+	RegisterBuiltin("push", builtinPush)
+	RegisterBuiltin("puts", builtinPuts)
+	RegisterBuiltin("type", builtinType)
+	RegisterBuiltin("int", builtinInt)
+	RegisterBuiltin("float", builtinFloat)
+}
+
+// RegisterBuiltin makes a Go function callable from Monkey code under the given name,
+// replacing any builtin already registered with that name. Bindings made with let still
+// shadow builtins. Builtins should be registered before any programs are evaluated or
+// compiled. Nil arguments, which Go code calling the builtin can pass for a value that
+// has none, reach fn as NULL.
+func RegisterBuiltin(name string, fn BuiltinFunction) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	builtins[name] = &Builtin{Name: name, Fn: nullArguments(fn)}
+}
+
+// nullArguments wraps fn to replace its nil arguments with NULL, copying them rather
+// than changing the caller's.
+func nullArguments(fn BuiltinFunction) BuiltinFunction {
+	return func(args ...Object) Object {
+		if slices.Contains(args, nil) {
+			args = slices.Clone(args)
+			for i, arg := range args {
+				if arg == nil {
+					args[i] = NULL
+				}
+			}
+		}
+		return fn(args...)
+	}
+}
+
+// UnregisterBuiltin removes the builtin registered under name, if there is one.
+func UnregisterBuiltin(name string) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	delete(builtins, name)
+}
+
+// LookupBuiltin returns the builtin registered under name.
+func LookupBuiltin(name string) (*Builtin, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	builtin, ok := builtins[name]
+	return builtin, ok
+}
+
+// SetOutput sets where the puts builtin writes, which is os.Stdout by default.
+func SetOutput(w io.Writer) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	output = w
+}
+
+// builtinLen returns the number of elements in an array, or characters in a string.
+func builtinLen(args ...Object) Object {
+	if err := CheckArity("len", args, 1); err != nil {
+		return err
+	}
+
+	switch arg := args[0].(type) {
+	case *String:
+		return &Integer{Value: int64(utf8.RuneCountInString(arg.Value))}
+	case *Array:
+		return &Integer{Value: int64(len(arg.Elements))}
+	default:
+		return NewError("argument to `len` not supported, got %s", args[0].Type())
+	}
+}
+
+// builtinFirst returns the first element of an array, or null if it is empty.
+func builtinFirst(args ...Object) Object {
+	array, err := arrayArgument("first", args)
+	if err != nil {
+		return err
+	}
+
+	if len(array.Elements) > 0 {
+		return array.Elements[0]
+	}
+	return NULL
+}
+
+// builtinLast returns the last element of an array, or null if it is empty.
+func builtinLast(args ...Object) Object {
+	array, err := arrayArgument("last", args)
+	if err != nil {
+		return err
+	}
+
+	length := len(array.Elements)
+	if length > 0 {
+		return array.Elements[length-1]
+	}
+	return NULL
+}
+
+// builtinRest returns a new array holding every element but the first, or null if it is empty.
+func builtinRest(args ...Object) Object {
+	array, err := arrayArgument("rest", args)
+	if err != nil {
+		return err
+	}
+
+	length := len(array.Elements)
+	if length > 0 {
+		elements := make([]Object, length-1)
+		copy(elements, array.Elements[1:length])
+		return &Array{Elements: elements}
+	}
+	return NULL
+}
+
+// builtinPush returns a new array with the second argument added to the end of the first.
+func builtinPush(args ...Object) Object {
+	if err := CheckArity("push", args, 2); err != nil {
+		return err
+	}
+	if args[0].Type() != ARRAY_OBJ {
+		return NewError("argument to `push` must be ARRAY, got %s", args[0].Type())
+	}
+
+	array := args[0].(*Array)
+	length := len(array.Elements)
+
+	elements := make([]Object, length+1)
+	copy(elements, array.Elements)
+	elements[length] = args[1]
+
+	return &Array{Elements: elements}
+}
+
+// arrayArgument checks that a builtin was called with exactly one array.
+func arrayArgument(name string, args []Object) (*Array, *Error) {
+	if err := CheckArity(name, args, 1); err != nil {
+		return nil, err
+	}
+	if args[0].Type() != ARRAY_OBJ {
+		return nil, NewError("argument to `%s` must be ARRAY, got %s", name, args[0].Type())
+	}
+	return args[0].(*Array), nil
+}
+
+// builtinPuts prints each argument on its own line.
+func builtinPuts(args ...Object) Object {
+	mu.RLock()
+	w := output
+	mu.RUnlock()
+
+	for _, arg := range args {
+		fmt.Fprintln(w, arg.Inspect())
+	}
+	return NULL
+}
+
+// builtinInt converts a number or a string of decimal digits to an integer. Floats are
+// truncated toward zero.
+func builtinInt(args ...Object) Object {
+	if err := CheckArity("int", args, 1); err != nil {
+		return err
+	}
+
+	switch arg := args[0].(type) {
+	case *Integer, *BigInt:
+		return arg
+	case *Float:
+		if math.IsNaN(arg.Value) || math.IsInf(arg.Value, 0) {
+			return NewError("cannot convert %s to an integer", arg.Inspect())
+		}
+		value, _ := big.NewFloat(arg.Value).Int(nil)
+		return NewInteger(value)
+	case *String:
+		value, ok := new(big.Int).SetString(strings.TrimSpace(arg.Value), 10)
+		if !ok {
+			return NewError("cannot convert %q to an integer", arg.Value)
+		}
+		return NewInteger(value)
+	default:
+		return NewError("argument to `int` not supported, got %s", args[0].Type())
+	}
+}
+
+// builtinFloat converts a number or a numeric string to a float.
+func builtinFloat(args ...Object) Object {
+	if err := CheckArity("float", args, 1); err != nil {
+		return err
+	}
+
+	switch arg := args[0].(type) {
+	case *Integer, *BigInt, *Float:
+		value, _ := FloatValue(arg)
+		return &Float{Value: value}
+	case *String:
+		value, err := strconv.ParseFloat(strings.TrimSpace(arg.Value), 64)
+		if err != nil && !errors.Is(err, strconv.ErrRange) {
+			return NewError("cannot convert %q to a float", arg.Value)
+		}
+		return &Float{Value: value}
+	default:
+		return NewError("argument to `float` not supported, got %s", args[0].Type())
+	}
+}
+
+// builtinType returns the name of the argument's type, such as "INTEGER".
+func builtinType(args ...Object) Object {
+	if err := CheckArity("type", args, 1); err != nil {
+		return err
+	}
+	return &String{Value: string(args[0].Type())}
+}
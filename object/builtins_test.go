@@ -0,0 +1,34 @@
+package object
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestBuiltinsTakeNilArguments(t *testing.T) {
+	var out strings.Builder
+	SetOutput(&out)
+	t.Cleanup(func() { SetOutput(os.Stdout) })
+
+	for name, builtin := range builtins {
+		t.Run(name, func(t *testing.T) {
+			args := []Object{nil, nil}
+			if name != "puts" && name != "push" {
+				args = args[:1]
+			}
+
+			result := builtin.Fn(args...)
+			if result == nil {
+				t.Fatalf("no result")
+			}
+			if args[0] != nil {
+				t.Errorf("arguments changed")
+			}
+		})
+	}
+
+	if out.String() != "null\nnull\n" {
+		t.Errorf("wrong output. got=%q", out.String())
+	}
+}
@@ -0,0 +1,40 @@
+package object
+
+import (
+	"fmt"
+	"github.com/jacksonopp/monkey/code"
+)
+
+// CompiledFunction is a function literal compiled to bytecode for the vm.
+type CompiledFunction struct {
+	Instructions  code.Instructions
+	NumLocals     int
+	NumParameters int
+	Name          string // the name the function was bound to with let, if any
+}
+
+func (c CompiledFunction) Type() ObjectType {
+	return COMPILED_FUNCTION_OBJ
+}
+
+func (c *CompiledFunction) Inspect() string {
+	return fmt.Sprintf("CompiledFunction[%p]", c)
+}
+
+// Closure is a CompiledFunction together with the free variables it captured.
+// To Monkey code it is just a function.
+type Closure struct {
+	Fn   *CompiledFunction
+	Free []Object
+}
+
+func (c Closure) Type() ObjectType {
+	return FUNCTION_OBJ
+}
+
+func (c *Closure) Inspect() string {
+	if c.Fn.Name != "" {
+		return fmt.Sprintf("fn %s[%p]", c.Fn.Name, c)
+	}
+	return fmt.Sprintf("fn[%p]", c)
+}
@@ -0,0 +1,15 @@
+package object
+
+// CONTINUE is returned by a continue statement and passed up through blocks until it
+// reaches the loop it moves on to the next iteration.
+var CONTINUE = &Continue{}
+
+type Continue struct{}
+
+func (c Continue) Type() ObjectType {
+	return CONTINUE_OBJ
+}
+
+func (c Continue) Inspect() string {
+	return "continue"
+}
@@ -0,0 +1,557 @@
+package object
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// The conversions between Go values and Monkey objects follow encoding/json: numbers,
+// booleans and strings convert to their Monkey counterparts, slices and arrays to arrays,
+// and maps and structs to hashes. Struct fields are named by a `monkey:"name"` tag, or by
+// the field's own name without one, and a tag of "-" leaves the field out. Unexported
+// fields are always left out.
+
+var (
+	objectType = reflect.TypeOf((*Object)(nil)).Elem()
+	bigIntType = reflect.TypeOf((*big.Int)(nil))
+)
+
+// FromGo converts a Go value to a Monkey object. Nil converts to NULL, and funcs are
+// wrapped as builtins with NewGoBuiltin. Objects are returned as they are, and values
+// that contain themselves, through pointers, maps or slices, cannot be converted.
+func FromGo(value interface{}) (Object, error) {
+	if obj, ok := value.(Object); ok {
+		return obj, nil
+	}
+	if value == nil {
+		return NULL, nil
+	}
+	return fromGo(reflect.ValueOf(value), visiting{})
+}
+
+// visiting holds the values a conversion is inside of, so that a value that contains itself
+// is reported instead of converted forever. Monkey arrays and hashes are held as they are,
+// and Go pointers, maps and slices by a goReference.
+type visiting map[interface{}]bool
+
+// enter adds key to s, reporting false if it is already there.
+func (s visiting) enter(key interface{}) bool {
+	if s[key] {
+		return false
+	}
+	s[key] = true
+	return true
+}
+
+func (s visiting) leave(key interface{}) {
+	delete(s, key)
+}
+
+// goReference identifies the Go value a pointer, map or slice refers to. The type and
+// length tell apart values that start at the same address, like a struct and its first
+// field.
+type goReference struct {
+	ptr uintptr
+	t   reflect.Type
+	len int
+}
+
+func newGoReference(v reflect.Value) goReference {
+	ref := goReference{ptr: v.Pointer(), t: v.Type()}
+	if v.Kind() == reflect.Slice {
+		ref.len = v.Len()
+	}
+	return ref
+}
+
+func fromGo(v reflect.Value, seen visiting) (Object, error) {
+	if v.Type() == bigIntType {
+		if v.IsNil() {
+			return NULL, nil
+		}
+		return NewInteger(new(big.Int).Set(v.Interface().(*big.Int))), nil
+	}
+	if v.Type().Implements(objectType) && !isNil(v) {
+		return v.Interface().(Object), nil
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			return TRUE, nil
+		}
+		return FALSE, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &Integer{Value: v.Int()}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return NewInteger(new(big.Int).SetUint64(v.Uint())), nil
+	case reflect.Float32, reflect.Float64:
+		return &Float{Value: v.Float()}, nil
+	case reflect.String:
+		return &String{Value: v.String()}, nil
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice {
+			if v.IsNil() {
+				return NULL, nil
+			}
+			ref := newGoReference(v)
+			if !seen.enter(ref) {
+				return nil, goContainsItself(v)
+			}
+			defer seen.leave(ref)
+		}
+		elements := make([]Object, v.Len())
+		for i := range elements {
+			element, err := fromGo(v.Index(i), seen)
+			if err != nil {
+				return nil, err
+			}
+			elements[i] = element
+		}
+		return &Array{Elements: elements}, nil
+	case reflect.Map:
+		if v.IsNil() {
+			return NULL, nil
+		}
+		return mapFromGo(v, seen)
+	case reflect.Struct:
+		return structFromGo(v, seen)
+	case reflect.Pointer:
+		if v.IsNil() {
+			return NULL, nil
+		}
+		ref := newGoReference(v)
+		if !seen.enter(ref) {
+			return nil, goContainsItself(v)
+		}
+		defer seen.leave(ref)
+		return fromGo(v.Elem(), seen)
+	case reflect.Interface:
+		if v.IsNil() {
+			return NULL, nil
+		}
+		return fromGo(v.Elem(), seen)
+	case reflect.Func:
+		if v.IsNil() {
+			return NULL, nil
+		}
+		return NewGoBuiltin(funcName(v), v.Interface())
+	default:
+		return nil, fmt.Errorf("cannot convert Go value of type %s to a Monkey object", v.Type())
+	}
+}
+
+// mapFromGo converts a map to a hash, ordering its keys so that the hash is the same each
+// time the map is converted.
+func mapFromGo(v reflect.Value, seen visiting) (Object, error) {
+	ref := newGoReference(v)
+	if !seen.enter(ref) {
+		return nil, goContainsItself(v)
+	}
+	defer seen.leave(ref)
+
+	type pair struct {
+		key   Hashable
+		value Object
+	}
+	pairs := []pair{}
+
+	iter := v.MapRange()
+	for iter.Next() {
+		key, err := fromGo(iter.Key(), seen)
+		if err != nil {
+			return nil, err
+		}
+		hashKey, ok := key.(Hashable)
+		if !ok {
+			return nil, fmt.Errorf("cannot convert Go map with %s keys to a Monkey hash", v.Type().Key())
+		}
+
+		value, err := fromGo(iter.Value(), seen)
+		if err != nil {
+			return nil, err
+		}
+		pairs = append(pairs, pair{key: hashKey, value: value})
+	}
+
+	sort.Slice(pairs, func(i, j int) bool {
+		return lessKey(pairs[i].key.(Object), pairs[j].key.(Object))
+	})
+
+	hash := NewHash()
+	for _, p := range pairs {
+		hash.Set(p.key, p.value)
+	}
+	return hash, nil
+}
+
+// lessKey orders the keys of a hash converted from a map: integers by value, and
+// everything else by type and then by how it prints.
+func lessKey(a, b Object) bool {
+	if x, ok := BigValue(a); ok {
+		if y, ok := BigValue(b); ok {
+			return x.Cmp(y) < 0
+		}
+	}
+	if a.Type() != b.Type() {
+		return a.Type() < b.Type()
+	}
+	return a.Inspect() < b.Inspect()
+}
+
+func structFromGo(v reflect.Value, seen visiting) (Object, error) {
+	hash := NewHash()
+
+	for _, field := range reflect.VisibleFields(v.Type()) {
+		name, ok := fieldName(field)
+		if !ok {
+			continue
+		}
+
+		fv, err := v.FieldByIndexErr(field.Index)
+		if err != nil {
+			// the field is promoted through a nil embedded pointer
+			continue
+		}
+
+		value, err := fromGo(fv, seen)
+		if err != nil {
+			return nil, err
+		}
+		hash.Set(&String{Value: name}, value)
+	}
+
+	return hash, nil
+}
+
+// fieldName returns the name of a struct field in a hash, reporting false for fields that
+// are left out.
+func fieldName(field reflect.StructField) (string, bool) {
+	if !field.IsExported() || field.Anonymous {
+		return "", false
+	}
+
+	tag := field.Tag.Get("monkey")
+	if tag == "-" {
+		return "", false
+	}
+	if name, _, _ := strings.Cut(tag, ","); name != "" {
+		return name, true
+	}
+	return field.Name, true
+}
+
+// ToGo converts a Monkey object to a Go value: an int64, *big.Int, float64, bool, string
+// or nil, a []interface{} for an array, and for a hash a map[string]interface{} when all
+// of its keys are strings, or a map[interface{}]interface{} otherwise. Functions cannot
+// be converted, and neither can arrays and hashes that contain themselves.
+func ToGo(obj Object) (interface{}, error) {
+	return toInterface(obj, visiting{})
+}
+
+func toInterface(obj Object, seen visiting) (interface{}, error) {
+	switch obj := obj.(type) {
+	case *Integer:
+		return obj.Value, nil
+	case *BigInt:
+		return new(big.Int).Set(obj.Value), nil
+	case *Float:
+		return obj.Value, nil
+	case *Boolean:
+		return obj.Value, nil
+	case *String:
+		return obj.Value, nil
+	case *Null:
+		return nil, nil
+	case *Array:
+		if !seen.enter(obj) {
+			return nil, containsItself(obj)
+		}
+		defer seen.leave(obj)
+
+		elements := make([]interface{}, len(obj.Elements))
+		for i, element := range obj.Elements {
+			value, err := toInterface(element, seen)
+			if err != nil {
+				return nil, err
+			}
+			elements[i] = value
+		}
+		return elements, nil
+	case *Hash:
+		return hashToGo(obj, seen)
+	default:
+		return nil, fmt.Errorf("cannot convert %s to a Go value", obj.Type())
+	}
+}
+
+func hashToGo(hash *Hash, seen visiting) (interface{}, error) {
+	if !seen.enter(hash) {
+		return nil, containsItself(hash)
+	}
+	defer seen.leave(hash)
+
+	stringKeys := true
+	for _, pair := range hash.Pairs {
+		if pair.Key.Type() != STRING_OBJ {
+			stringKeys = false
+		}
+	}
+
+	byString := map[string]interface{}{}
+	byValue := map[interface{}]interface{}{}
+
+	for _, pair := range hash.Pairs {
+		value, err := toInterface(pair.Value, seen)
+		if err != nil {
+			return nil, err
+		}
+
+		if stringKeys {
+			byString[pair.Key.(*String).Value] = value
+			continue
+		}
+
+		k, err := toInterface(pair.Key, seen)
+		if err != nil {
+			return nil, err
+		}
+		if n, ok := k.(*big.Int); ok {
+			// pointers make poor map keys, so big integers are keyed by their digits
+			k = n.String()
+		}
+		byValue[k] = value
+	}
+
+	if stringKeys {
+		return byString, nil
+	}
+	return byValue, nil
+}
+
+// ToGoValue converts a Monkey object to the Go value target points to, which can be of
+// any type FromGo converts from, including structs. Numbers are checked to fit.
+func ToGoValue(obj Object, target interface{}) error {
+	v := reflect.ValueOf(target)
+	if v.Kind() != reflect.Pointer || v.IsNil() {
+		return fmt.Errorf("cannot convert %s to a Go value: target must be a non-nil pointer, got %T", obj.Type(), target)
+	}
+
+	value, err := toGo(obj, v.Type().Elem(), visiting{})
+	if err != nil {
+		return err
+	}
+	v.Elem().Set(value)
+	return nil
+}
+
+// toGo converts obj to a Go value of type t.
+func toGo(obj Object, t reflect.Type, seen visiting) (reflect.Value, error) {
+	if t.Implements(objectType) && reflect.TypeOf(obj).AssignableTo(t) {
+		return reflect.ValueOf(obj), nil
+	}
+
+	if t == bigIntType {
+		value, ok := BigValue(obj)
+		if !ok {
+			return reflect.Value{}, cannotConvert(obj, t)
+		}
+		return reflect.ValueOf(new(big.Int).Set(value)), nil
+	}
+
+	if obj == NULL {
+		switch t.Kind() {
+		case reflect.Pointer, reflect.Interface, reflect.Slice, reflect.Map:
+			return reflect.Zero(t), nil
+		}
+		return reflect.Value{}, cannotConvert(obj, t)
+	}
+
+	switch t.Kind() {
+	case reflect.Interface:
+		value, err := toInterface(obj, seen)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		if !reflect.TypeOf(value).AssignableTo(t) {
+			return reflect.Value{}, cannotConvert(obj, t)
+		}
+		return reflect.ValueOf(value).Convert(t), nil
+	case reflect.Bool:
+		if b, ok := obj.(*Boolean); ok {
+			return reflect.ValueOf(b.Value).Convert(t), nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if i, ok := obj.(*Integer); ok {
+			v := reflect.New(t).Elem()
+			if v.OverflowInt(i.Value) {
+				return reflect.Value{}, fmt.Errorf("%d overflows %s", i.Value, t)
+			}
+			v.SetInt(i.Value)
+			return v, nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if value, ok := BigValue(obj); ok {
+			v := reflect.New(t).Elem()
+			if value.Sign() < 0 || !value.IsUint64() || v.OverflowUint(value.Uint64()) {
+				return reflect.Value{}, fmt.Errorf("%s overflows %s", value, t)
+			}
+			v.SetUint(value.Uint64())
+			return v, nil
+		}
+	case reflect.Float32, reflect.Float64:
+		if f, ok := FloatValue(obj); ok {
+			return reflect.ValueOf(f).Convert(t), nil
+		}
+	case reflect.String:
+		if s, ok := obj.(*String); ok {
+			return reflect.ValueOf(s.Value).Convert(t), nil
+		}
+	case reflect.Slice, reflect.Array:
+		if array, ok := obj.(*Array); ok {
+			return arrayToGo(array, t, seen)
+		}
+	case reflect.Map:
+		if hash, ok := obj.(*Hash); ok {
+			return mapToGo(hash, t, seen)
+		}
+	case reflect.Struct:
+		if hash, ok := obj.(*Hash); ok {
+			return structToGo(hash, t, seen)
+		}
+	case reflect.Pointer:
+		value, err := toGo(obj, t.Elem(), seen)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		ptr := reflect.New(t.Elem())
+		ptr.Elem().Set(value)
+		return ptr, nil
+	}
+
+	return reflect.Value{}, cannotConvert(obj, t)
+}
+
+func arrayToGo(array *Array, t reflect.Type, seen visiting) (reflect.Value, error) {
+	if !seen.enter(array) {
+		return reflect.Value{}, containsItself(array)
+	}
+	defer seen.leave(array)
+
+	var v reflect.Value
+	if t.Kind() == reflect.Array {
+		if t.Len() != len(array.Elements) {
+			return reflect.Value{}, fmt.Errorf("cannot convert array of %d elements to %s", len(array.Elements), t)
+		}
+		v = reflect.New(t).Elem()
+	} else {
+		v = reflect.MakeSlice(t, len(array.Elements), len(array.Elements))
+	}
+
+	for i, element := range array.Elements {
+		value, err := toGo(element, t.Elem(), seen)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		v.Index(i).Set(value)
+	}
+	return v, nil
+}
+
+func mapToGo(hash *Hash, t reflect.Type, seen visiting) (reflect.Value, error) {
+	if !seen.enter(hash) {
+		return reflect.Value{}, containsItself(hash)
+	}
+	defer seen.leave(hash)
+
+	v := reflect.MakeMapWithSize(t, len(hash.Pairs))
+
+	for _, pair := range hash.Pairs {
+		k, err := toGo(pair.Key, t.Key(), seen)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		value, err := toGo(pair.Value, t.Elem(), seen)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		v.SetMapIndex(k, value)
+	}
+	return v, nil
+}
+
+// structToGo fills the fields of a struct from the pairs of a hash with the same names,
+// leaving fields the hash has no pair for at their zero value.
+func structToGo(hash *Hash, t reflect.Type, seen visiting) (reflect.Value, error) {
+	if !seen.enter(hash) {
+		return reflect.Value{}, containsItself(hash)
+	}
+	defer seen.leave(hash)
+
+	v := reflect.New(t).Elem()
+
+	for _, field := range reflect.VisibleFields(t) {
+		name, ok := fieldName(field)
+		if !ok {
+			continue
+		}
+
+		pair, ok := hash.Get(&String{Value: name})
+		if !ok {
+			continue
+		}
+
+		value, err := toGo(pair, field.Type, seen)
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("field %s: %w", name, err)
+		}
+		fv, err := fieldByIndex(v, field.Index)
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("field %s: %w", name, err)
+		}
+		fv.Set(value)
+	}
+	return v, nil
+}
+
+// fieldByIndex returns the field of v with the given index, like v.FieldByIndex, but
+// allocates the nil embedded pointers it is promoted through.
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, error) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return reflect.Value{}, fmt.Errorf("cannot set embedded pointer to unexported struct %s", v.Type().Elem())
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, nil
+}
+
+// isNil reports whether v is a nil pointer or interface.
+func isNil(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		return v.IsNil()
+	}
+	return false
+}
+
+func cannotConvert(obj Object, t reflect.Type) error {
+	return fmt.Errorf("cannot convert %s to %s", obj.Type(), t)
+}
+
+func containsItself(obj Object) error {
+	return fmt.Errorf("cannot convert %s that contains itself", obj.Type())
+}
+
+func goContainsItself(v reflect.Value) error {
+	return fmt.Errorf("cannot convert Go value of type %s that contains itself", v.Type())
+}
@@ -0,0 +1,286 @@
+package object
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+)
+
+type node struct {
+	Next *node
+}
+
+type Inner struct {
+	X int
+}
+
+type inner struct {
+	Z int
+}
+
+type embedded struct {
+	*Inner
+	Y int
+}
+
+type unexportedEmbedded struct {
+	*inner
+}
+
+type point struct {
+	X      int    `monkey:"x"`
+	Y      int    `monkey:"y"`
+	Label  string // untagged fields keep their name
+	Hidden bool   `monkey:"-"`
+	secret int
+}
+
+func TestFromGo(t *testing.T) {
+	var nilSlice []int
+	var nilPointer *point
+
+	tests := []struct {
+		name     string
+		value    interface{}
+		expected string
+	}{
+		{"nil", nil, "null"},
+		{"int", 5, "5"},
+		{"int8", int8(-5), "-5"},
+		{"uint64", uint64(1) << 63, "9223372036854775808"},
+		{"big int", big.NewInt(7), "7"},
+		{"float", 2.5, "2.5"},
+		{"bool", true, "true"},
+		{"string", "hello", "hello"},
+		{"slice", []int{1, 2, 3}, "[1, 2, 3]"},
+		{"array", [2]string{"a", "b"}, "[a, b]"},
+		{"nil slice", nilSlice, "null"},
+		{"map", map[string]int{"b": 2, "a": 1}, "{a: 1, b: 2}"},
+		{"integer keys", map[int]bool{10: true, 2: false}, "{2: false, 10: true}"},
+		{"struct", point{X: 1, Y: 2, Label: "p", Hidden: true, secret: 3}, "{x: 1, y: 2, Label: p}"},
+		{"pointer", &point{X: 1}, "{x: 1, y: 0, Label: }"},
+		{"nil pointer", nilPointer, "null"},
+		{"embedded pointer", embedded{Inner: &Inner{X: 1}, Y: 2}, "{X: 1, Y: 2}"},
+		{"nil embedded pointer", embedded{Y: 2}, "{Y: 2}"},
+		{"nested", []interface{}{1, "a", []bool{true}, nil}, "[1, a, [true], null]"},
+		{"object", &Integer{Value: 7}, "7"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obj, err := FromGo(tt.value)
+			if err != nil {
+				t.Fatalf("FromGo failed: %s", err)
+			}
+			if obj.Inspect() != tt.expected {
+				t.Errorf("wrong object. want=%q, got=%q", tt.expected, obj.Inspect())
+			}
+		})
+	}
+
+	t.Run("contains itself", func(t *testing.T) {
+		list := &node{}
+		list.Next = &node{Next: list}
+
+		_, err := FromGo(list)
+		if err == nil || err.Error() != "cannot convert Go value of type *object.node that contains itself" {
+			t.Errorf("wrong error. got=%v", err)
+		}
+	})
+
+	t.Run("shared values", func(t *testing.T) {
+		shared := &point{X: 1}
+
+		obj, err := FromGo([]*point{shared, shared})
+		if err != nil {
+			t.Fatalf("FromGo failed: %s", err)
+		}
+		if obj.Inspect() != "[{x: 1, y: 0, Label: }, {x: 1, y: 0, Label: }]" {
+			t.Errorf("wrong object. got=%q", obj.Inspect())
+		}
+	})
+
+	t.Run("unsupported", func(t *testing.T) {
+		_, err := FromGo(make(chan int))
+		if err == nil || err.Error() != "cannot convert Go value of type chan int to a Monkey object" {
+			t.Errorf("wrong error. got=%v", err)
+		}
+	})
+}
+
+func TestToGo(t *testing.T) {
+	tests := []struct {
+		name     string
+		obj      Object
+		expected interface{}
+	}{
+		{"integer", &Integer{Value: 5}, int64(5)},
+		{"big integer", &BigInt{Value: new(big.Int).Lsh(big.NewInt(1), 64)}, new(big.Int).Lsh(big.NewInt(1), 64)},
+		{"float", &Float{Value: 2.5}, 2.5},
+		{"boolean", TRUE, true},
+		{"string", &String{Value: "hello"}, "hello"},
+		{"null", NULL, nil},
+		{
+			"array",
+			newArray(&Integer{Value: 1}, &String{Value: "a"}, newArray(TRUE)),
+			[]interface{}{int64(1), "a", []interface{}{true}},
+		},
+		{
+			"string keys",
+			newHash(&String{Value: "a"}, &Integer{Value: 1}, &String{Value: "b"}, newArray(&Integer{Value: 2})),
+			map[string]interface{}{"a": int64(1), "b": []interface{}{int64(2)}},
+		},
+		{
+			"other keys",
+			newHash(&Integer{Value: 1}, &String{Value: "one"}, TRUE, &String{Value: "yes"}),
+			map[interface{}]interface{}{int64(1): "one", true: "yes"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, err := ToGo(tt.obj)
+			if err != nil {
+				t.Fatalf("ToGo failed: %s", err)
+			}
+			if !reflect.DeepEqual(value, tt.expected) {
+				t.Errorf("wrong value. want=%#v, got=%#v", tt.expected, value)
+			}
+		})
+	}
+
+	t.Run("contains itself", func(t *testing.T) {
+		array := newArray(&Integer{Value: 1})
+		array.Elements[0] = array
+
+		hash := newHash(&String{Value: "a"}, NULL)
+		hash.Set(&String{Value: "a"}, newArray(hash))
+
+		tests := []struct {
+			obj      Object
+			expected string
+		}{
+			{array, "cannot convert ARRAY that contains itself"},
+			{hash, "cannot convert HASH that contains itself"},
+		}
+
+		for _, tt := range tests {
+			if _, err := ToGo(tt.obj); err == nil || err.Error() != tt.expected {
+				t.Errorf("wrong error from ToGo. want=%q, got=%v", tt.expected, err)
+			}
+
+			var value interface{}
+			if err := ToGoValue(tt.obj, &value); err == nil || err.Error() != tt.expected {
+				t.Errorf("wrong error from ToGoValue. want=%q, got=%v", tt.expected, err)
+			}
+		}
+	})
+
+	t.Run("shared values", func(t *testing.T) {
+		shared := newArray(&Integer{Value: 1})
+
+		value, err := ToGo(newArray(shared, shared))
+		if err != nil {
+			t.Fatalf("ToGo failed: %s", err)
+		}
+		expected := []interface{}{[]interface{}{int64(1)}, []interface{}{int64(1)}}
+		if !reflect.DeepEqual(value, expected) {
+			t.Errorf("wrong value. want=%#v, got=%#v", expected, value)
+		}
+	})
+
+	t.Run("function", func(t *testing.T) {
+		_, err := ToGo(&Function{})
+		if err == nil || err.Error() != "cannot convert FUNCTION to a Go value" {
+			t.Errorf("wrong error. got=%v", err)
+		}
+	})
+}
+
+func TestToGoValue(t *testing.T) {
+	t.Run("struct", func(t *testing.T) {
+		obj := newHash(
+			&String{Value: "x"}, &Integer{Value: 1},
+			&String{Value: "y"}, &Integer{Value: 2},
+			&String{Value: "Label"}, &String{Value: "p"},
+			&String{Value: "Hidden"}, TRUE,
+		)
+
+		var p point
+		if err := ToGoValue(obj, &p); err != nil {
+			t.Fatalf("ToGoValue failed: %s", err)
+		}
+		if p != (point{X: 1, Y: 2, Label: "p"}) {
+			t.Errorf("wrong struct. got=%+v", p)
+		}
+	})
+
+	t.Run("embedded pointer", func(t *testing.T) {
+		obj := newHash(&String{Value: "X"}, &Integer{Value: 1}, &String{Value: "Y"}, &Integer{Value: 2})
+
+		var e embedded
+		if err := ToGoValue(obj, &e); err != nil {
+			t.Fatalf("ToGoValue failed: %s", err)
+		}
+		if e.Inner == nil || e.X != 1 || e.Y != 2 {
+			t.Errorf("wrong struct. got=%+v", e)
+		}
+	})
+
+	t.Run("typed collections", func(t *testing.T) {
+		obj := newHash(
+			&String{Value: "a"}, newArray(&Integer{Value: 1}, &Integer{Value: 2}),
+			&String{Value: "b"}, newArray(),
+		)
+
+		var m map[string][]float64
+		if err := ToGoValue(obj, &m); err != nil {
+			t.Fatalf("ToGoValue failed: %s", err)
+		}
+		expected := map[string][]float64{"a": {1, 2}, "b": {}}
+		if !reflect.DeepEqual(m, expected) {
+			t.Errorf("wrong map. want=%v, got=%v", expected, m)
+		}
+	})
+
+	errorTests := []struct {
+		name     string
+		obj      Object
+		target   interface{}
+		expected string
+	}{
+		{"wrong type", &String{Value: "a"}, new(int), "cannot convert STRING to int"},
+		{"overflow", &Integer{Value: 300}, new(uint8), "300 overflows uint8"},
+		{"negative unsigned", &Integer{Value: -1}, new(uint), "-1 overflows uint"},
+		{"field", newHash(&String{Value: "x"}, &String{Value: "a"}), new(point), "field x: cannot convert STRING to int"},
+		{
+			"unexported embedded pointer",
+			newHash(&String{Value: "Z"}, &Integer{Value: 1}),
+			new(unexportedEmbedded),
+			"field Z: cannot set embedded pointer to unexported struct object.inner",
+		},
+		{"not a pointer", &Integer{Value: 1}, 0, "cannot convert INTEGER to a Go value: target must be a non-nil pointer, got int"},
+	}
+
+	for _, tt := range errorTests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ToGoValue(tt.obj, tt.target)
+			if err == nil || err.Error() != tt.expected {
+				t.Errorf("wrong error. want=%q, got=%v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func newArray(elements ...Object) *Array {
+	return &Array{Elements: elements}
+}
+
+// newHash returns a hash of the keys and values in pairs, which alternate.
+func newHash(pairs ...Object) *Hash {
+	hash := NewHash()
+	for i := 0; i < len(pairs); i += 2 {
+		hash.Set(pairs[i].(Hashable), pairs[i+1])
+	}
+	return hash
+}
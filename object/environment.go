@@ -1,12 +1,18 @@
 package object
 
 type Environment struct {
-	store map[string]Object
+	store map[string]binding
 	outer *Environment
 }
 
+// binding is a value bound to a name, which cannot be changed when it is constant.
+type binding struct {
+	value    Object
+	constant bool
+}
+
 func NewEnvironment() *Environment {
-	s := make(map[string]Object)
+	s := make(map[string]binding)
 	return &Environment{store: s, outer: nil}
 }
 
@@ -17,14 +23,59 @@ func NewEnclosedEnvironment(outer *Environment) *Environment {
 }
 
 func (e *Environment) Get(name string) (Object, bool) {
-	obj, ok := e.store[name]
+	b, ok := e.store[name]
 	if !ok && e.outer != nil {
-		obj, ok = e.outer.Get(name)
+		return e.outer.Get(name)
 	}
-	return obj, ok
+	return b.value, ok
 }
 
+// Set binds name to val in this environment, replacing any binding it already has here.
+// Callers declaring a variable should check IsConstant first.
 func (e *Environment) Set(name string, val Object) Object {
-	e.store[name] = val
+	e.store[name] = binding{value: val}
+	return val
+}
+
+// SetConstant binds name to val in this environment as a constant that cannot be
+// assigned to or declared again in the same environment.
+func (e *Environment) SetConstant(name string, val Object) Object {
+	e.store[name] = binding{value: val, constant: true}
 	return val
 }
+
+// IsConstant reports whether name is bound as a constant in this environment, not
+// counting the environments enclosing it.
+func (e *Environment) IsConstant(name string) bool {
+	return e.store[name].constant
+}
+
+// Assign updates the binding of name in the nearest environment that has one, walking
+// out through the enclosing environments. It returns an error when name is not bound or
+// is bound to a constant.
+func (e *Environment) Assign(name string, val Object) *Error {
+	if b, ok := e.store[name]; ok {
+		if b.constant {
+			return NewError("cannot assign to constant %s", name)
+		}
+		e.store[name] = binding{value: val}
+		return nil
+	}
+	if e.outer != nil {
+		return e.outer.Assign(name, val)
+	}
+	return NewError("assignment to undeclared identifier: %s", name)
+}
+
+// Outer returns the environment e is enclosed in, or nil if it is not enclosed.
+func (e *Environment) Outer() *Environment {
+	return e.outer
+}
+
+// Each calls fn with each name bound in e and its value, not counting the environments
+// enclosing it.
+func (e *Environment) Each(fn func(name string, val Object)) {
+	for name, b := range e.store {
+		fn(name, b.value)
+	}
+}
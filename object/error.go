@@ -1,19 +1,133 @@
 package object
 
-import "fmt"
+import (
+	"fmt"
+	"github.com/jacksonopp/monkey/token"
+	"strings"
+)
 
 type Error struct {
 	Message string
+	Pos     token.Position // where in the source the error occurred, if known
+	Stack   []Frame        // the calls the error propagated out of, innermost first
+	Cause   error          // why evaluation was stopped, for errors raised by the host rather than the program
+	GoErr   error          // the error returned by the Go function that raised this error, if any
+}
+
+// Frame is a function call that was in progress when an error occurred.
+type Frame struct {
+	Function string         // the name of the function called, empty if it has none
+	Pos      token.Position // where the function was called from
+	Args     int            // how many arguments it was called with
+	TailCall bool           // the call was made in tail position after calls in between were replaced, so its caller is unknown
 }
 
 func NewError(format string, a ...interface{}) *Error {
 	return &Error{Message: fmt.Sprintf(format, a...)}
 }
 
+// NewArityError reports a call with the wrong number of arguments to the function called
+// name, which is empty for anonymous functions.
+func NewArityError(name string, want, got int) *Error {
+	return NewArityRangeError(name, want, want, got)
+}
+
+// NewArityRangeError reports a call to a function that takes between min and max
+// arguments, where a negative max means there is no upper limit.
+func NewArityRangeError(name string, min, max, got int) *Error {
+	var want string
+	switch {
+	case max < 0:
+		want = fmt.Sprintf(">=%d", min)
+	case min == max:
+		want = fmt.Sprintf("=%d", min)
+	default:
+		want = fmt.Sprintf("=%d..%d", min, max)
+	}
+
+	if name == "" {
+		return NewError("wrong number of arguments: want%s, got=%d", want, got)
+	}
+	return NewError("wrong number of arguments to `%s`: want%s, got=%d", name, want, got)
+}
+
 func (e Error) Type() ObjectType {
 	return ERROR_OBJ
 }
 
 func (e Error) Inspect() string {
+	if e.Pos.IsValid() {
+		return "ERROR: " + e.Pos.String() + ": " + e.Message
+	}
 	return "ERROR: " + e.Message
 }
+
+// Traceback formats the error along with the calls it propagated out of, outermost
+// first, the way Python prints a traceback. Runs of the same call, as left by a runaway
+// recursion, are printed once. Errors outside of any call format the same as Inspect.
+func (e Error) Traceback() string {
+	if len(e.Stack) == 0 {
+		return e.Inspect()
+	}
+
+	lines := []string{}
+	for i := len(e.Stack) - 1; i >= 0; i-- {
+		frame := e.Stack[i]
+
+		caller := "<program>"
+		if !frame.Pos.IsValid() {
+			caller = "<host>"
+		}
+		if i+1 < len(e.Stack) {
+			caller = frameName(e.Stack[i+1].Function)
+		}
+
+		arguments := "arguments"
+		if frame.Args == 1 {
+			arguments = "argument"
+		}
+
+		if frame.TailCall {
+			// the function the call was made from was itself replaced, so it is unknown
+			lines = append(lines, "  (...tail calls...)\n")
+			lines = append(lines, fmt.Sprintf("  %scalled %s with %d %s\n", location(frame.Pos), frameName(frame.Function), frame.Args, arguments))
+			continue
+		}
+
+		lines = append(lines, fmt.Sprintf("  %sin %s, called %s with %d %s\n", location(frame.Pos), caller, frameName(frame.Function), frame.Args, arguments))
+	}
+
+	var out strings.Builder
+
+	out.WriteString("Traceback (most recent call last):\n")
+	for i := 0; i < len(lines); {
+		out.WriteString(lines[i])
+
+		repeats := i + 1
+		for repeats < len(lines) && lines[repeats] == lines[i] {
+			repeats += 1
+		}
+		if repeats-i > 1 {
+			fmt.Fprintf(&out, "  [previous line repeated %d more times]\n", repeats-i-1)
+		}
+		i = repeats
+	}
+	out.WriteString(e.Inspect())
+
+	return out.String()
+}
+
+// location prefixes a line of a traceback with pos, when the call was made from source.
+func location(pos token.Position) string {
+	if !pos.IsValid() {
+		return ""
+	}
+	return pos.String() + ": "
+}
+
+func frameName(function string) string {
+	if function == "" {
+		return "<anonymous>"
+	}
+	return "`" + function + "`"
+}
@@ -0,0 +1,45 @@
+package object
+
+import (
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+type Float struct {
+	Value float64
+}
+
+func (f Float) Type() ObjectType {
+	return FLOAT_OBJ
+}
+
+// Inspect formats the float so that it can be told apart from an integer, as in 3.0.
+func (f Float) Inspect() string {
+	s := strconv.FormatFloat(f.Value, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".eIN") {
+		s += ".0"
+	}
+	return s
+}
+
+// FloatValue returns the value of an Integer, BigInt or Float as a float64. It reports
+// false for any other object.
+func FloatValue(obj Object) (float64, bool) {
+	switch obj := obj.(type) {
+	case *Integer:
+		return float64(obj.Value), true
+	case *BigInt:
+		value, _ := new(big.Float).SetInt(obj.Value).Float64()
+		return value, true
+	case *Float:
+		return obj.Value, true
+	default:
+		return 0, false
+	}
+}
+
+// IsNumber reports whether obj is an Integer, BigInt or Float.
+func IsNumber(obj Object) bool {
+	return IsInteger(obj) || obj.Type() == FLOAT_OBJ
+}
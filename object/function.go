@@ -3,13 +3,15 @@ package object
 import (
 	"bytes"
 	"github.com/jacksonopp/monkey/ast"
-	"strings"
 )
 
 type Function struct {
 	Parameters []*ast.Identifier
+	Defaults   []ast.Expression // the default value of each parameter, nil where it has none
+	Rest       *ast.Identifier  // the ...rest parameter collecting extra arguments, if any
 	Body       *ast.BlockStatement
 	Env        *Environment
+	Name       string // the name the function was bound to with let or const, if any
 }
 
 func (f Function) Type() ObjectType {
@@ -19,15 +21,9 @@ func (f Function) Type() ObjectType {
 func (f Function) Inspect() string {
 	var out bytes.Buffer
 
-	params := []string{}
-
-	for _, p := range f.Parameters {
-		params = append(params, p.String())
-	}
-
 	out.WriteString("fn")
 	out.WriteString("(")
-	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(ast.ParameterList(f.Parameters, f.Defaults, f.Rest))
 	out.WriteString(") {\n")
 	out.WriteString(f.Body.String())
 	out.WriteString("\n}")
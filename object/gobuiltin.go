@@ -0,0 +1,106 @@
+package object
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// NewGoBuiltin wraps the Go func fn as a builtin called name. Its arguments are converted
+// to the types of fn's parameters as ToGoValue does, and calls with the wrong number of
+// arguments or with arguments that do not convert return an error. A non-nil error as
+// fn's last result is returned as an error holding it in GoErr; otherwise a single result
+// is converted with FromGo, several are returned as an array, and none as NULL.
+func NewGoBuiltin(name string, fn interface{}) (*Builtin, error) {
+	v := reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func || v.IsNil() {
+		return nil, fmt.Errorf("cannot wrap Go value of type %T as a builtin", fn)
+	}
+
+	return &Builtin{Name: name, Fn: goFunction(name, v)}, nil
+}
+
+func goFunction(name string, fn reflect.Value) BuiltinFunction {
+	t := fn.Type()
+
+	return func(args ...Object) (result Object) {
+		if err := checkGoArity(name, t, args); err != nil {
+			return err
+		}
+
+		in := make([]reflect.Value, len(args))
+		for i, arg := range args {
+			paramType := goParameterType(t, i)
+
+			value, err := toGo(arg, paramType, visiting{})
+			if err != nil {
+				return NewError("argument %d to `%s` must be %s, got %s", i+1, name, paramType, arg.Type())
+			}
+			in[i] = value
+		}
+
+		defer func() {
+			if r := recover(); r != nil {
+				result = NewError("`%s` panicked: %v", name, r)
+			}
+		}()
+
+		return goResult(name, fn.Call(in))
+	}
+}
+
+func checkGoArity(name string, t reflect.Type, args []Object) *Error {
+	if t.IsVariadic() {
+		if len(args) < t.NumIn()-1 {
+			return NewArityRangeError(name, t.NumIn()-1, -1, len(args))
+		}
+		return nil
+	}
+	return CheckArity(name, args, t.NumIn())
+}
+
+// goParameterType returns the type of the i'th argument to a func of type t, which for the
+// arguments to a variadic parameter is the type of its elements.
+func goParameterType(t reflect.Type, i int) reflect.Type {
+	if t.IsVariadic() && i >= t.NumIn()-1 {
+		return t.In(t.NumIn() - 1).Elem()
+	}
+	return t.In(i)
+}
+
+func goResult(name string, out []reflect.Value) Object {
+	if n := len(out); n > 0 && out[n-1].Type() == errorType {
+		if err, _ := out[n-1].Interface().(error); err != nil {
+			return &Error{Message: err.Error(), GoErr: err}
+		}
+		out = out[:n-1]
+	}
+
+	results := make([]Object, len(out))
+	for i, value := range out {
+		result, err := fromGo(value, visiting{})
+		if err != nil {
+			return NewError("result of `%s`: %s", name, err)
+		}
+		results[i] = result
+	}
+
+	switch len(results) {
+	case 0:
+		return NULL
+	case 1:
+		return results[0]
+	default:
+		return &Array{Elements: results}
+	}
+}
+
+// funcName returns the name a func is declared with, without its package.
+func funcName(fn reflect.Value) string {
+	name := runtime.FuncForPC(fn.Pointer()).Name()
+	name = strings.TrimSuffix(name, "-fm")
+	return name[strings.LastIndex(name, ".")+1:]
+}
@@ -0,0 +1,92 @@
+package object
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestNewGoBuiltin(t *testing.T) {
+	errFailed := errors.New("failed")
+
+	tests := []struct {
+		name     string
+		fn       interface{}
+		args     []Object
+		expected string
+	}{
+		{"arguments", strings.Repeat, []Object{&String{Value: "ab"}, &Integer{Value: 3}}, "ababab"},
+		{"no results", func() {}, nil, "null"},
+		{"several results", func(a, b int) (int, int) { return b, a }, []Object{&Integer{Value: 1}, &Integer{Value: 2}}, "[2, 1]"},
+		{
+			"variadic",
+			func(sep string, parts ...string) string { return strings.Join(parts, sep) },
+			[]Object{&String{Value: "-"}, &String{Value: "a"}, &String{Value: "b"}},
+			"a-b",
+		},
+		{
+			"struct argument",
+			func(p point) int { return p.X + p.Y },
+			[]Object{newHash(&String{Value: "x"}, &Integer{Value: 1}, &String{Value: "y"}, &Integer{Value: 2})},
+			"3",
+		},
+		{"float from integer", func(f float64) float64 { return f / 2 }, []Object{&Integer{Value: 3}}, "1.5"},
+		{"nil error", func() (string, error) { return "ok", nil }, nil, "ok"},
+		{"error", func() (string, error) { return "", errFailed }, nil, "ERROR: failed"},
+		{"wrong number of arguments", strings.Repeat, []Object{&String{Value: "ab"}}, "ERROR: wrong number of arguments to `f`: want=2, got=1"},
+		{"too few variadic arguments", func(int, ...int) {}, nil, "ERROR: wrong number of arguments to `f`: want>=1, got=0"},
+		{
+			"wrong argument type",
+			strings.Repeat,
+			[]Object{&String{Value: "ab"}, &String{Value: "3"}},
+			"ERROR: argument 2 to `f` must be int, got STRING",
+		},
+		{"panic", func() { panic("boom") }, nil, "ERROR: `f` panicked: boom"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			builtin, err := NewGoBuiltin("f", tt.fn)
+			if err != nil {
+				t.Fatalf("NewGoBuiltin failed: %s", err)
+			}
+
+			result := builtin.Fn(tt.args...)
+			if result.Inspect() != tt.expected {
+				t.Errorf("wrong result. want=%q, got=%q", tt.expected, result.Inspect())
+			}
+		})
+	}
+
+	t.Run("returned error", func(t *testing.T) {
+		builtin, err := NewGoBuiltin("f", func() error { return errFailed })
+		if err != nil {
+			t.Fatalf("NewGoBuiltin failed: %s", err)
+		}
+
+		errObj, ok := builtin.Fn().(*Error)
+		if !ok {
+			t.Fatalf("result is not Error. got=%T", builtin.Fn())
+		}
+		if errObj.GoErr != errFailed || errObj.Cause != nil {
+			t.Errorf("wrong errors. GoErr=%v, Cause=%v", errObj.GoErr, errObj.Cause)
+		}
+	})
+
+	t.Run("name", func(t *testing.T) {
+		builtin, err := FromGo(strings.ToUpper)
+		if err != nil {
+			t.Fatalf("FromGo failed: %s", err)
+		}
+		if builtin.Inspect() != "builtin function ToUpper" {
+			t.Errorf("wrong builtin. got=%q", builtin.Inspect())
+		}
+	})
+
+	t.Run("not a func", func(t *testing.T) {
+		_, err := NewGoBuiltin("f", 5)
+		if err == nil || err.Error() != "cannot wrap Go value of type int as a builtin" {
+			t.Errorf("wrong error. got=%v", err)
+		}
+	})
+}
@@ -0,0 +1,134 @@
+package object
+
+import (
+	"bytes"
+	"hash/fnv"
+	"strings"
+)
+
+// Hashable is implemented by the objects that can be used as keys in a Hash.
+type Hashable interface {
+	HashKey() HashKey
+}
+
+type HashKey struct {
+	Type  ObjectType
+	Value uint64
+}
+
+func (i Integer) HashKey() HashKey {
+	return HashKey{Type: i.Type(), Value: uint64(i.Value)}
+}
+
+func (b Boolean) HashKey() HashKey {
+	var value uint64
+
+	if b.Value {
+		value = 1
+	}
+
+	return HashKey{Type: b.Type(), Value: value}
+}
+
+func (s String) HashKey() HashKey {
+	h := fnv.New64a()
+	h.Write([]byte(s.Value))
+
+	return HashKey{Type: s.Type(), Value: h.Sum64()}
+}
+
+type HashPair struct {
+	Key   Object
+	Value Object
+}
+
+type Hash struct {
+	Pairs   []HashPair        // the pairs in insertion order
+	buckets map[HashKey][]int // the indexes in Pairs of the keys with each HashKey
+}
+
+func NewHash() *Hash {
+	return &Hash{buckets: make(map[HashKey][]int)}
+}
+
+// Get returns the value stored under key.
+func (h *Hash) Get(key Hashable) (Object, bool) {
+	if i, ok := h.index(key); ok {
+		return h.Pairs[i].Value, true
+	}
+	return nil, false
+}
+
+// Set stores value under key, keeping the position of a key that is already present.
+func (h *Hash) Set(key Hashable, value Object) {
+	if i, ok := h.index(key); ok {
+		h.Pairs[i].Value = value
+		return
+	}
+
+	hashKey := key.HashKey()
+	h.buckets[hashKey] = append(h.buckets[hashKey], len(h.Pairs))
+	h.Pairs = append(h.Pairs, HashPair{Key: key.(Object), Value: value})
+}
+
+// index returns the position in Pairs of key. Keys whose HashKeys collide share a bucket,
+// and are told apart by comparing them.
+func (h *Hash) index(key Hashable) (int, bool) {
+	for _, i := range h.buckets[key.HashKey()] {
+		if sameKey(h.Pairs[i].Key, key.(Object)) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// sameKey reports whether two keys with the same HashKey are equal.
+func sameKey(a, b Object) bool {
+	switch a := a.(type) {
+	case *Integer:
+		b, ok := b.(*Integer)
+		return ok && a.Value == b.Value
+	case *BigInt:
+		b, ok := b.(*BigInt)
+		return ok && a.Value.Cmp(b.Value) == 0
+	case *Boolean:
+		b, ok := b.(*Boolean)
+		return ok && a.Value == b.Value
+	case *String:
+		b, ok := b.(*String)
+		return ok && a.Value == b.Value
+	default:
+		return a.Type() == b.Type() && a.Inspect() == b.Inspect()
+	}
+}
+
+func (h Hash) Type() ObjectType {
+	return HASH_OBJ
+}
+
+func (h Hash) Inspect() string {
+	return h.inspect(visiting{})
+}
+
+// inspect prints the hash, printing a hash it is already inside of as {...}.
+func (h Hash) inspect(seen visiting) string {
+	if len(h.Pairs) > 0 {
+		if !seen.enter(&h.Pairs[0]) {
+			return "{...}"
+		}
+		defer seen.leave(&h.Pairs[0])
+	}
+
+	var out bytes.Buffer
+
+	pairs := []string{}
+	for _, pair := range h.Pairs {
+		pairs = append(pairs, pair.Key.Inspect()+": "+inspect(pair.Value, seen))
+	}
+
+	out.WriteString("{")
+	out.WriteString(strings.Join(pairs, ", "))
+	out.WriteString("}")
+
+	return out.String()
+}
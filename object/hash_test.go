@@ -0,0 +1,60 @@
+package object
+
+import "testing"
+
+// collidingKey is a key whose HashKey is the same for every value.
+type collidingKey struct {
+	Value string
+}
+
+func (c *collidingKey) Type() ObjectType { return "COLLIDING" }
+func (c *collidingKey) Inspect() string  { return c.Value }
+func (c *collidingKey) HashKey() HashKey { return HashKey{Type: c.Type()} }
+
+func TestHashCollisions(t *testing.T) {
+	hash := NewHash()
+	hash.Set(&collidingKey{"a"}, &Integer{Value: 1})
+	hash.Set(&collidingKey{"b"}, &Integer{Value: 2})
+	hash.Set(&collidingKey{"a"}, &Integer{Value: 3})
+
+	if hash.Inspect() != "{a: 3, b: 2}" {
+		t.Errorf("wrong hash. got=%q", hash.Inspect())
+	}
+
+	tests := []struct {
+		key      string
+		expected string
+	}{
+		{"a", "3"},
+		{"b", "2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			value, ok := hash.Get(&collidingKey{tt.key})
+			if !ok || value.Inspect() != tt.expected {
+				t.Errorf("wrong value. want=%s, got=%v", tt.expected, value)
+			}
+		})
+	}
+
+	if _, ok := hash.Get(&collidingKey{"c"}); ok {
+		t.Errorf("missing key found")
+	}
+}
+
+func TestHashKeysOfDifferentTypes(t *testing.T) {
+	hash := NewHash()
+	hash.Set(&Integer{Value: 1}, &String{Value: "integer"})
+	hash.Set(&String{Value: "1"}, &String{Value: "string"})
+	hash.Set(TRUE, &String{Value: "boolean"})
+
+	if len(hash.Pairs) != 3 {
+		t.Fatalf("wrong number of pairs. got=%d", len(hash.Pairs))
+	}
+
+	value, ok := hash.Get(&Integer{Value: 1})
+	if !ok || value.Inspect() != "integer" {
+		t.Errorf("wrong value. got=%v", value)
+	}
+}
@@ -1,5 +1,8 @@
 package object
 
+// NULL is the only Null value; it can be compared by identity.
+var NULL = &Null{}
+
 type Null struct{}
 
 func (n Null) Type() ObjectType {
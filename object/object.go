@@ -4,11 +4,21 @@ type ObjectType string
 
 const (
 	INTEGER_OBJ      ObjectType = "INTEGER"
+	BIGINT_OBJ                  = "BIGINT"
+	FLOAT_OBJ                   = "FLOAT"
 	BOOLEAN_OBJ                 = "BOOLEAN"
 	NULL_OBJ                    = "NULL"
 	RETURN_VALUE_OBJ            = "RETURN_VALUE"
+	BREAK_OBJ                   = "BREAK"
+	CONTINUE_OBJ                = "CONTINUE"
 	ERROR_OBJ                   = "ERROR"
 	FUNCTION_OBJ                = "FUNCTION"
+	STRING_OBJ                  = "STRING"
+	ARRAY_OBJ                   = "ARRAY"
+	BUILTIN_OBJ                 = "BUILTIN"
+	HASH_OBJ                    = "HASH"
+
+	COMPILED_FUNCTION_OBJ = "COMPILED_FUNCTION"
 )
 
 type Object interface {
@@ -0,0 +1,13 @@
+package object
+
+type String struct {
+	Value string
+}
+
+func (s String) Type() ObjectType {
+	return STRING_OBJ
+}
+
+func (s String) Inspect() string {
+	return s.Value
+}
@@ -1,10 +1,12 @@
 package parser
 
 import (
+	"errors"
 	"fmt"
 	"github.com/jacksonopp/monkey/ast"
 	"github.com/jacksonopp/monkey/lexer"
 	"github.com/jacksonopp/monkey/token"
+	"math/big"
 	"strconv"
 )
 
@@ -14,8 +16,13 @@ type (
 )
 
 type Parser struct {
-	l      *lexer.Lexer // the lexer
-	errors []string
+	l         *lexer.Lexer // the lexer
+	errors    []string
+	eofErrors int // how many of errors were found at the end of the input
+
+	loopDepth     int               // how many loops the current statement is inside of, within its function
+	functionDepth int               // how many function literals the current statement is inside of
+	scopes        []map[string]bool // the names declared in each enclosing scope, and whether they are constant
 
 	curToken  token.Token // the current token being inspected
 	peekToken token.Token // the next token to be inspected
@@ -25,7 +32,7 @@ type Parser struct {
 }
 
 func New(l *lexer.Lexer) *Parser {
-	p := &Parser{l: l, errors: []string{}}
+	p := &Parser{l: l, errors: []string{}, scopes: []map[string]bool{{}}}
 
 	p.nextToken()
 	p.nextToken()
@@ -33,6 +40,8 @@ func New(l *lexer.Lexer) *Parser {
 	p.prefixParseFns = make(map[token.TokenType]prefixParseFn)
 	p.registerPrefix(token.IDENT, p.parseIdentifier)
 	p.registerPrefix(token.INT, p.parseIntegerLiteral)
+	p.registerPrefix(token.FLOAT, p.parseFloatLiteral)
+	p.registerPrefix(token.STRING, p.parseStringLiteral)
 	p.registerPrefix(token.BANG, p.parsePrefixExpression)
 	p.registerPrefix(token.MINUS, p.parsePrefixExpression)
 	p.registerPrefix(token.TRUE, p.parseBoolean)
@@ -40,22 +49,47 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerPrefix(token.LPAREN, p.parseGroupedExpression)
 	p.registerPrefix(token.IF, p.parseIfExpression)
 	p.registerPrefix(token.FUNCTION, p.parseFunctionLiteral)
+	p.registerPrefix(token.LBRACKET, p.parseArrayLiteral)
+	p.registerPrefix(token.LBRACE, p.parseHashLiteral)
 
 	p.infixParseFns = make(map[token.TokenType]infixParseFn)
 	p.registerInfix(token.PLUS, p.parseInfixExpression)
 	p.registerInfix(token.MINUS, p.parseInfixExpression)
 	p.registerInfix(token.SLASH, p.parseInfixExpression)
 	p.registerInfix(token.ASTERISK, p.parseInfixExpression)
+	p.registerInfix(token.PERCENT, p.parseInfixExpression)
 	p.registerInfix(token.EQ, p.parseInfixExpression)
 	p.registerInfix(token.NEQ, p.parseInfixExpression)
 	p.registerInfix(token.GT, p.parseInfixExpression)
 	p.registerInfix(token.LT, p.parseInfixExpression)
+	p.registerInfix(token.GT_EQ, p.parseInfixExpression)
+	p.registerInfix(token.LT_EQ, p.parseInfixExpression)
+	p.registerInfix(token.AND, p.parseInfixExpression)
+	p.registerInfix(token.OR, p.parseInfixExpression)
+	p.registerInfix(token.ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.PLUS_ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.MINUS_ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.ASTERISK_ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.SLASH_ASSIGN, p.parseAssignExpression)
 	p.registerInfix(token.LPAREN, p.parseCallExpression)
+	p.registerInfix(token.LBRACKET, p.parseIndexExpression)
 	return p
 }
 
+// Incomplete reports whether the input ended in the middle of a statement, for example
+// inside an unclosed block or string or after a trailing operator, so that more input
+// could still make it valid. It is false when there are other errors.
+func (p *Parser) Incomplete() bool {
+	if len(p.l.Errors()) > 0 && !p.l.Incomplete() {
+		return false
+	}
+	return len(p.Errors()) > 0 && p.eofErrors == len(p.errors)
+}
+
+// Errors returns the problems found while lexing and parsing the input.
 func (p *Parser) Errors() []string {
-	return p.errors
+	errors := append([]string{}, p.l.Errors()...)
+	return append(errors, p.errors...)
 }
 
 func (p *Parser) ParseProgram() *ast.Program {
@@ -141,9 +175,11 @@ func (p *Parser) parseIntegerLiteral() ast.Expression {
 	lit := &ast.IntegerLiteral{Token: p.curToken}
 
 	value, err := strconv.ParseInt(p.curToken.Literal, 0, 64)
+	if errors.Is(err, strconv.ErrRange) {
+		return p.parseBigIntLiteral()
+	}
 	if err != nil {
-		msg := fmt.Sprintf("could not parse %q as integer", p.curToken.Literal)
-		p.errors = append(p.errors, msg)
+		p.addError(p.curToken, "could not parse %q as integer", p.curToken.Literal)
 		return nil
 	}
 	lit.Value = value
@@ -151,6 +187,35 @@ func (p *Parser) parseIntegerLiteral() ast.Expression {
 	return lit
 }
 
+// parseBigIntLiteral parses an integer literal that is too large for an int64.
+func (p *Parser) parseBigIntLiteral() ast.Expression {
+	value, ok := new(big.Int).SetString(p.curToken.Literal, 0)
+	if !ok {
+		p.addError(p.curToken, "could not parse %q as integer", p.curToken.Literal)
+		return nil
+	}
+
+	return &ast.BigIntLiteral{Token: p.curToken, Value: value}
+}
+
+func (p *Parser) parseFloatLiteral() ast.Expression {
+	value, err := strconv.ParseFloat(p.curToken.Literal, 64)
+	if err != nil && !errors.Is(err, strconv.ErrRange) {
+		p.addError(p.curToken, "could not parse %q as float", p.curToken.Literal)
+		return nil
+	}
+
+	// literals out of range become infinity or zero like the results of arithmetic do
+	return &ast.FloatLiteral{Token: p.curToken, Value: value}
+}
+
+func (p *Parser) parseStringLiteral() ast.Expression {
+	return &ast.StringLiteral{
+		Token: p.curToken,
+		Value: p.curToken.Literal,
+	}
+}
+
 func (p *Parser) parseBoolean() ast.Expression {
 	return &ast.Boolean{
 		Token: p.curToken,
@@ -222,43 +287,94 @@ func (p *Parser) parseFunctionLiteral() ast.Expression {
 		return nil
 	}
 
-	lit.Parameters = p.parseFunctionParameters()
+	if !p.parseFunctionParameters(lit) {
+		return nil
+	}
 
 	if !p.expectPeek(token.LBRACE) {
 		return nil
 	}
 
+	p.enterScope()
+	for _, param := range lit.Parameters {
+		p.declare(param.Value, false)
+	}
+	if lit.Rest != nil {
+		p.declare(lit.Rest.Value, false)
+	}
+
+	// loops outside the function cannot be stopped from inside it
+	loopDepth := p.loopDepth
+	p.loopDepth = 0
+	p.functionDepth += 1
 	lit.Body = p.parseBlockStatement()
+	p.functionDepth -= 1
+	p.loopDepth = loopDepth
+
+	markTailCalls(lit.Body)
+
+	p.leaveScope()
 
 	return lit
 }
 
-func (p *Parser) parseFunctionParameters() []*ast.Identifier {
-	identifiers := []*ast.Identifier{}
+// parseFunctionParameters parses `(a, b = 10, ...rest)` in to lit. Parameters may have a
+// default value, and once one does, the ones after it must too. A rest parameter can only
+// come last.
+func (p *Parser) parseFunctionParameters(lit *ast.FunctionLiteral) bool {
+	lit.Parameters = []*ast.Identifier{}
+	lit.Defaults = []ast.Expression{}
 
 	// no params
 	if p.peekTokenIs(token.RPAREN) {
 		p.nextToken()
-		return identifiers
+		return true
 	}
 
-	p.nextToken()
+	seen := map[string]bool{}
+	for {
+		rest := p.peekTokenIs(token.ELLIPSIS)
+		if rest {
+			p.nextToken()
+		}
+		if !p.expectPeek(token.IDENT) {
+			return false
+		}
 
-	ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
-	identifiers = append(identifiers, ident)
+		ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		if seen[ident.Value] {
+			p.addError(ident.Token, "duplicate parameter %s", ident.Value)
+		}
+		seen[ident.Value] = true
+
+		if rest {
+			lit.Rest = ident
+			if !p.peekTokenIs(token.RPAREN) {
+				p.addError(p.peekToken, "rest parameter %s must be the last parameter", ident.Value)
+				return false
+			}
+			break
+		}
 
-	for p.peekTokenIs(token.COMMA) {
-		p.nextToken()
-		p.nextToken()
-		i := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
-		identifiers = append(identifiers, i)
-	}
+		var value ast.Expression
+		if p.peekTokenIs(token.ASSIGN) {
+			p.nextToken()
+			p.nextToken()
+			value = p.parseExpression(ASSIGN)
+		} else if len(lit.Defaults) > 0 && lit.Defaults[len(lit.Defaults)-1] != nil {
+			p.addError(ident.Token, "parameter %s without a default value follows one with a default", ident.Value)
+		}
 
-	if !p.expectPeek(token.RPAREN) {
-		return nil
+		lit.Parameters = append(lit.Parameters, ident)
+		lit.Defaults = append(lit.Defaults, value)
+
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		p.nextToken()
 	}
 
-	return identifiers
+	return p.expectPeek(token.RPAREN)
 }
 
 func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
@@ -267,6 +383,8 @@ func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
 	return exp
 }
 
+// parseCallArguments parses the arguments of a call. Arguments written as `name: value`
+// are passed by name, and must come after the positional ones.
 func (p *Parser) parseCallArguments() []ast.Expression {
 	args := []ast.Expression{}
 
@@ -275,20 +393,116 @@ func (p *Parser) parseCallArguments() []ast.Expression {
 		return args
 	}
 
+	named := map[string]bool{}
+	for {
+		p.nextToken()
+
+		if p.curTokenIs(token.IDENT) && p.peekTokenIs(token.COLON) {
+			arg := &ast.NamedArgument{Token: p.curToken}
+			arg.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+			if named[arg.Name.Value] {
+				p.addError(arg.Token, "duplicate argument %s", arg.Name.Value)
+			}
+			named[arg.Name.Value] = true
+
+			p.nextToken()
+			p.nextToken()
+			arg.Value = p.parseExpression(LOWEST)
+			args = append(args, arg)
+		} else {
+			if len(named) > 0 {
+				p.addError(p.curToken, "positional argument after named arguments")
+			}
+			args = append(args, p.parseExpression(LOWEST))
+		}
+
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		p.nextToken()
+	}
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	return args
+}
+
+func (p *Parser) parseArrayLiteral() ast.Expression {
+	array := &ast.ArrayLiteral{Token: p.curToken}
+	array.Elements = p.parseExpressionList(token.RBRACKET)
+	return array
+}
+
+// parseHashLiteral parses `{key: value, ...}`. Braces only start a hash literal in expression
+// position; the bodies of functions and ifs are parsed as block statements directly.
+func (p *Parser) parseHashLiteral() ast.Expression {
+	hash := &ast.HashLiteral{Token: p.curToken}
+	hash.Pairs = []ast.HashPair{}
+
+	for !p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
+		key := p.parseExpression(LOWEST)
+
+		if !p.expectPeek(token.COLON) {
+			return nil
+		}
+
+		p.nextToken()
+		value := p.parseExpression(LOWEST)
+
+		hash.Pairs = append(hash.Pairs, ast.HashPair{Key: key, Value: value})
+
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.RBRACE) {
+		return nil
+	}
+
+	return hash
+}
+
+func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
+	exp := &ast.IndexExpression{Token: p.curToken, Left: left}
+
 	p.nextToken()
-	args = append(args, p.parseExpression(LOWEST))
+	exp.Index = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RBRACKET) {
+		return nil
+	}
+
+	return exp
+}
+
+// parseExpressionList parses a comma separated list of expressions up to the end token,
+// as used by call arguments and array literals.
+func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
+	list := []ast.Expression{}
+
+	if p.peekTokenIs(end) {
+		p.nextToken()
+		return list
+	}
+
+	p.nextToken()
+	list = append(list, p.parseExpression(LOWEST))
 
 	for p.peekTokenIs(token.COMMA) {
 		p.nextToken()
 		p.nextToken()
-		args = append(args, p.parseExpression(LOWEST))
+		list = append(list, p.parseExpression(LOWEST))
 	}
 
-	if !p.expectPeek(token.RPAREN) {
+	if !p.expectPeek(end) {
 		return nil
 	}
 
-	return args
+	return list
 }
 
 func (p *Parser) parseBlockStatement() *ast.BlockStatement {
@@ -304,12 +518,16 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 		}
 		p.nextToken()
 	}
+
+	if p.curTokenIs(token.EOF) {
+		p.addError(p.curToken, "expected %s to close the block, got %s instead", token.RBRACE, token.EOF)
+	}
+
 	return block
 }
 
 func (p *Parser) noPrefixParseFnError(t token.TokenType) {
-	msg := fmt.Sprintf("no prefix parse function for %s found", t)
-	p.errors = append(p.errors, msg)
+	p.addError(p.curToken, "no prefix parse function for %s found", t)
 }
 
 func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
@@ -326,6 +544,31 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 	return expression
 }
 
+// parseAssignExpression parses an assignment to the variable or index expression on its
+// left. Assignments are right associative, so `a = b = 1` assigns 1 to both.
+func (p *Parser) parseAssignExpression(target ast.Expression) ast.Expression {
+	expression := &ast.AssignExpression{
+		Token:    p.curToken,
+		Target:   target,
+		Operator: p.curToken.Literal,
+	}
+
+	switch target := target.(type) {
+	case *ast.Identifier:
+		if p.resolvesToConstant(target.Value) {
+			p.addError(target.Token, "cannot assign to constant %s", target.Value)
+		}
+	case *ast.IndexExpression:
+	default:
+		p.addError(p.curToken, "cannot assign to %s", target.String())
+		return nil
+	}
+
+	p.nextToken()
+	expression.Value = p.parseExpression(ASSIGN - 1)
+	return expression
+}
+
 // registerPrefix is a helper method that adds a prefixParseFn to the map of prefixParseFns
 func (p *Parser) registerPrefix(tokenType token.TokenType, fn prefixParseFn) {
 	p.prefixParseFns[tokenType] = fn
@@ -344,13 +587,116 @@ func (p *Parser) parseStatement() ast.Statement {
 	switch p.curToken.Type {
 	case token.LET:
 		return p.parseLetStatement()
+	case token.CONST:
+		return p.parseConstStatement()
 	case token.RETURN:
 		return p.parseReturnStatement()
+	case token.WHILE:
+		return p.parseWhileStatement()
+	case token.FOR:
+		return p.parseForStatement()
+	case token.BREAK, token.CONTINUE:
+		return p.parseLoopControlStatement()
 	default:
 		return p.parseExpressionStatement()
 	}
 }
 
+func (p *Parser) parseWhileStatement() *ast.WhileStatement {
+	stmt := &ast.WhileStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	p.nextToken()
+	stmt.Condition = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	p.enterScope()
+	stmt.Body = p.parseLoopBody()
+	p.leaveScope()
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseForStatement() *ast.ForStatement {
+	stmt := &ast.ForStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	stmt.Variable = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	if !p.expectPeek(token.IN) {
+		return nil
+	}
+
+	p.nextToken()
+	stmt.Iterable = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	p.enterScope()
+	p.declare(stmt.Variable.Value, false)
+	stmt.Body = p.parseLoopBody()
+	p.leaveScope()
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+// parseLoopBody parses the block of a loop, in which break and continue are allowed.
+func (p *Parser) parseLoopBody() *ast.BlockStatement {
+	p.loopDepth += 1
+	defer func() { p.loopDepth -= 1 }()
+
+	return p.parseBlockStatement()
+}
+
+// parseLoopControlStatement parses break and continue, which must be inside a loop in
+// the same function.
+func (p *Parser) parseLoopControlStatement() ast.Statement {
+	tok := p.curToken
+
+	if p.loopDepth == 0 {
+		p.addError(tok, "%s outside of a loop", tok.Literal)
+	}
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	if tok.Type == token.BREAK {
+		return &ast.BreakStatement{Token: tok}
+	}
+	return &ast.ContinueStatement{Token: tok}
+}
+
 func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 	stmt := &ast.ReturnStatement{
 		Token: p.curToken,
@@ -360,7 +706,11 @@ func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 
 	stmt.ReturnValue = p.parseExpression(LOWEST)
 
-	for !p.curTokenIs(token.SEMICOLON) {
+	if p.functionDepth > 0 {
+		markTailCall(stmt.ReturnValue)
+	}
+
+	if p.peekTokenIs(token.SEMICOLON) {
 		p.nextToken()
 	}
 	return stmt
@@ -386,13 +736,50 @@ func (p *Parser) parseLetStatement() *ast.LetStatement {
 
 	stmt.Value = p.parseExpression(LOWEST)
 
-	for !p.curTokenIs(token.SEMICOLON) {
+	if p.peekTokenIs(token.SEMICOLON) {
 		p.nextToken()
 	}
 
+	p.declareName(stmt.Name, false)
+
 	return stmt
 }
 
+func (p *Parser) parseConstStatement() *ast.ConstStatement {
+	stmt := &ast.ConstStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+
+	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	if !p.expectPeek(token.ASSIGN) {
+		return nil
+	}
+
+	p.nextToken()
+
+	stmt.Value = p.parseExpression(LOWEST)
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	p.declareName(stmt.Name, true)
+
+	return stmt
+}
+
+// declareName records a let or const binding, reporting an error when it would replace
+// a constant declared in the same scope.
+func (p *Parser) declareName(name *ast.Identifier, constant bool) {
+	if p.isDeclaredConstant(name.Value) {
+		p.addError(name.Token, "cannot redeclare constant %s", name.Value)
+	}
+	p.declare(name.Value, constant)
+}
+
 // curTokenIs checks if the current token is a certain token.TokenType.
 // see peekTokenIs
 func (p *Parser) curTokenIs(t token.TokenType) bool {
@@ -406,8 +793,15 @@ func (p *Parser) peekTokenIs(t token.TokenType) bool {
 }
 
 func (p *Parser) peekError(t token.TokenType) {
-	msg := fmt.Sprintf("expected next token to be %s, got %s instead", t, p.peekToken.Type)
-	p.errors = append(p.errors, msg)
+	p.addError(p.peekToken, "expected next token to be %s, got %s instead", t, p.peekToken.Type)
+}
+
+// addError records a parser error found at tok, prefixing it with the token's position.
+func (p *Parser) addError(tok token.Token, format string, a ...interface{}) {
+	if tok.Type == token.EOF {
+		p.eofErrors += 1
+	}
+	p.errors = append(p.errors, tok.Pos.String()+": "+fmt.Sprintf(format, a...))
 }
 
 // expectPeek checks if the next token is a certain token.TokenType
@@ -427,4 +821,9 @@ func (p *Parser) expectPeek(t token.TokenType) bool {
 func (p *Parser) nextToken() {
 	p.curToken = p.peekToken
 	p.peekToken = p.l.NextToken()
+
+	// comments are only kept for tools working with tokens, they mean nothing here
+	for p.peekToken.Type == token.COMMENT {
+		p.peekToken = p.l.NextToken()
+	}
 }
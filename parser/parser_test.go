@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/jacksonopp/monkey/ast"
 	"github.com/jacksonopp/monkey/lexer"
+	"reflect"
 	"testing"
 )
 
@@ -66,6 +67,20 @@ let foobar = 838383;
 				}
 			}
 		})
+		t.Run("errors include positions", func(t *testing.T) {
+			input := `let x = 5;
+let = 10;`
+
+			l := lexer.NewFile("test.mk", input)
+			p := New(l)
+			p.ParseProgram()
+
+			expected := "test.mk:2:5: expected next token to be IDENT, got = instead"
+			errors := p.Errors()
+			if len(errors) == 0 || errors[0] != expected {
+				t.Errorf("wrong errors. want first=%q, got=%q", expected, errors)
+			}
+		})
 		t.Run("let statements with expressions", func(t *testing.T) {
 			tests := []struct {
 				name               string
@@ -184,54 +199,234 @@ return 123123;`
 			}
 		})
 
-		// FIXME This test causes suite to hang?
-		//t.Run("return statment with expressions", func(t *testing.T) {
-		//	tests := []struct {
-		//		name          string
-		//		input         string
-		//		expectedValue interface{}
-		//	}{
-		//		{
-		//			"return number",
-		//			"return 5;",
-		//			5,
-		//		},
-		//		{
-		//			"return bool",
-		//			"return true;",
-		//			true,
-		//		},
-		//		{
-		//			"return variable",
-		//			"return foobar",
-		//			"foobar",
-		//		},
-		//	}
-		//
-		//	for _, tt := range tests {
-		//		t.Run(tt.name, func(t *testing.T) {
-		//			l := lexer.New(tt.input)
-		//			p := New(l)
-		//			program := p.ParseProgram()
-		//
-		//			checkParserErrors(t, p)
-		//			checkProgramStatementsLength(t, program.Statements, 1)
-		//			stmt := program.Statements[0]
-		//
-		//			returnStmt, ok := stmt.(*ast.ReturnStatement)
-		//			if !ok {
-		//				t.Fatalf("stmt not *ast.ReturnStatement. got=%T", stmt)
-		//			}
-		//			if returnStmt.TokenLiteral() != "return" {
-		//				t.Fatalf("returnStmtn.TokenLiteral not 'return'. got=%q", returnStmt.TokenLiteral())
-		//			}
-		//
-		//			if testLiteralExpression(t, returnStmt.ReturnValue, tt.expectedValue) {
-		//				return
-		//			}
-		//		})
-		//	}
-		//})
+		t.Run("return statement with optional semicolons", func(t *testing.T) {
+			tests := []struct {
+				name          string
+				input         string
+				expectedValue interface{}
+			}{
+				{
+					"return number",
+					"return 5;",
+					5,
+				},
+				{
+					"return bool",
+					"return true;",
+					true,
+				},
+				{
+					"return variable",
+					"return foobar",
+					"foobar",
+				},
+			}
+
+			for _, tt := range tests {
+				t.Run(tt.name, func(t *testing.T) {
+					l := lexer.New(tt.input)
+					p := New(l)
+					program := p.ParseProgram()
+
+					checkParserErrors(t, p)
+					checkProgramStatementsLength(t, program.Statements, 1)
+					stmt := program.Statements[0]
+
+					returnStmt, ok := stmt.(*ast.ReturnStatement)
+					if !ok {
+						t.Fatalf("stmt not *ast.ReturnStatement. got=%T", stmt)
+					}
+					if returnStmt.TokenLiteral() != "return" {
+						t.Fatalf("returnStmtn.TokenLiteral not 'return'. got=%q", returnStmt.TokenLiteral())
+					}
+
+					if testLiteralExpression(t, returnStmt.ReturnValue, tt.expectedValue) {
+						return
+					}
+				})
+			}
+		})
+	})
+
+	t.Run("const statement", func(t *testing.T) {
+		input := "const answer = 6 * 7;"
+
+		l := lexer.New(input)
+		p := New(l)
+		program := p.ParseProgram()
+
+		checkParserErrors(t, p)
+		checkProgramStatementsLength(t, program.Statements, 1)
+
+		stmt, ok := program.Statements[0].(*ast.ConstStatement)
+		if !ok {
+			t.Fatalf("stmt not *ast.ConstStatement. got=%T", program.Statements[0])
+		}
+		testIdentifier(t, stmt.Name, "answer")
+		testInfixExpression(t, stmt.Value, 6, "*", 7)
+
+		if stmt.String() != "const answer = (6 * 7);" {
+			t.Errorf("stmt.String() wrong. got=%q", stmt.String())
+		}
+	})
+
+	t.Run("constant errors", func(t *testing.T) {
+		tests := []struct {
+			name           string
+			input          string
+			expectedErrors []string
+		}{
+			{"assign", "const x = 1;\nx = 2;", []string{"2:1: cannot assign to constant x"}},
+			{"compound assign", "const x = 1; x += 2;", []string{"1:14: cannot assign to constant x"}},
+			{"assign in function", "const x = 1; fn() { x = 2 }", []string{"1:21: cannot assign to constant x"}},
+			{"redeclare with let", "const x = 1; let x = 2;", []string{"1:18: cannot redeclare constant x"}},
+			{"redeclare with const", "const x = 1; const x = 2;", []string{"1:20: cannot redeclare constant x"}},
+			{"shadow in function", "const x = 1; fn(x) { x = 2; let y = x; }", nil},
+			{"shadow with let in function", "const x = 1; fn() { let x = 2; x = 3; }", nil},
+			{"shadow in loop", "const x = 1; for (x in [1]) { x = 2 }", nil},
+			{"let can be redeclared", "let x = 1; let x = 2; x = 3; const x = 4;", nil},
+			{"index assign to constant", "const a = [1]; a[0] = 2;", nil},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				p := New(lexer.New(tt.input))
+				p.ParseProgram()
+
+				errors := p.Errors()
+				if len(errors) != len(tt.expectedErrors) {
+					t.Fatalf("wrong number of errors. want=%q, got=%q", tt.expectedErrors, errors)
+				}
+				for i, expected := range tt.expectedErrors {
+					if errors[i] != expected {
+						t.Errorf("wrong error. want=%q, got=%q", expected, errors[i])
+					}
+				}
+			})
+		}
+	})
+
+	t.Run("while statement", func(t *testing.T) {
+		input := "while (x < 10) { x; break; }"
+
+		l := lexer.New(input)
+		p := New(l)
+		program := p.ParseProgram()
+
+		checkParserErrors(t, p)
+		checkProgramStatementsLength(t, program.Statements, 1)
+
+		stmt, ok := program.Statements[0].(*ast.WhileStatement)
+		if !ok {
+			t.Fatalf("stmt not *ast.WhileStatement. got=%T", program.Statements[0])
+		}
+
+		testInfixExpression(t, stmt.Condition, "x", "<", 10)
+
+		if len(stmt.Body.Statements) != 2 {
+			t.Fatalf("body does not have 2 statements. got=%d", len(stmt.Body.Statements))
+		}
+		if _, ok := stmt.Body.Statements[1].(*ast.BreakStatement); !ok {
+			t.Errorf("body.Statements[1] not *ast.BreakStatement. got=%T", stmt.Body.Statements[1])
+		}
+	})
+
+	t.Run("for statement", func(t *testing.T) {
+		input := "for (x in [1, 2]) { if (x) { continue; } }"
+
+		l := lexer.New(input)
+		p := New(l)
+		program := p.ParseProgram()
+
+		checkParserErrors(t, p)
+		checkProgramStatementsLength(t, program.Statements, 1)
+
+		stmt, ok := program.Statements[0].(*ast.ForStatement)
+		if !ok {
+			t.Fatalf("stmt not *ast.ForStatement. got=%T", program.Statements[0])
+		}
+
+		testIdentifier(t, stmt.Variable, "x")
+
+		if stmt.Iterable.String() != "[1, 2]" {
+			t.Errorf("stmt.Iterable wrong. got=%q", stmt.Iterable.String())
+		}
+		if stmt.String() != "for (x in [1, 2]) ifx continue; " {
+			t.Errorf("stmt.String() wrong. got=%q", stmt.String())
+		}
+	})
+
+	t.Run("loops followed by a semicolon", func(t *testing.T) {
+		tests := []struct {
+			name  string
+			input string
+		}{
+			{"while", "while (x) { x }; x"},
+			{"for", "for (x in y) { x }; x"},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				p := New(lexer.New(tt.input))
+				program := p.ParseProgram()
+
+				checkParserErrors(t, p)
+				checkProgramStatementsLength(t, program.Statements, 2)
+
+				if _, ok := program.Statements[1].(*ast.ExpressionStatement); !ok {
+					t.Errorf("program.Statements[1] not *ast.ExpressionStatement. got=%T", program.Statements[1])
+				}
+			})
+		}
+	})
+
+	t.Run("assignment errors", func(t *testing.T) {
+		tests := []struct {
+			name          string
+			input         string
+			expectedError string
+		}{
+			{"literal target", "5 = x", "1:3: cannot assign to 5"},
+			{"call target", "f() += 1", "1:5: cannot assign to f()"},
+			{"missing value", "x =", "1:4: no prefix parse function for EOF found"},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				p := New(lexer.New(tt.input))
+				p.ParseProgram()
+
+				errors := p.Errors()
+				if len(errors) == 0 || errors[0] != tt.expectedError {
+					t.Errorf("wrong errors. want first=%q, got=%q", tt.expectedError, errors)
+				}
+			})
+		}
+	})
+
+	t.Run("loop control errors", func(t *testing.T) {
+		tests := []struct {
+			name          string
+			input         string
+			expectedError string
+		}{
+			{"break outside loop", "break;", "1:1: break outside of a loop"},
+			{"continue outside loop", "if (true) { continue }", "1:13: continue outside of a loop"},
+			{"break in function inside loop", "while (true) { fn() { break; } }", "1:23: break outside of a loop"},
+			{"for without in", "for (x [1]) {}", "1:8: expected next token to be IN, got [ instead"},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				p := New(lexer.New(tt.input))
+				p.ParseProgram()
+
+				errors := p.Errors()
+				if len(errors) == 0 || errors[0] != tt.expectedError {
+					t.Errorf("wrong errors. want first=%q, got=%q", tt.expectedError, errors)
+				}
+			})
+		}
 	})
 }
 
@@ -298,6 +493,93 @@ func TestExpressions(t *testing.T) {
 		checkProgramStatementsLength(t, program.Statements, 1)
 	})
 
+	t.Run("big integer literal expression", func(t *testing.T) {
+		input := "99999999999999999999;"
+
+		l := lexer.New(input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+		checkProgramStatementsLength(t, program.Statements, 1)
+		stmt := checkStatementIsExpressionStatement(t, program.Statements[0])
+
+		literal, ok := stmt.Expression.(*ast.BigIntLiteral)
+		if !ok {
+			t.Fatalf("exp not *ast.BigIntLiteral. got=%T", stmt.Expression)
+		}
+		if literal.Value.String() != "99999999999999999999" {
+			t.Errorf("literal.Value not %s. got=%s", "99999999999999999999", literal.Value)
+		}
+		if literal.TokenLiteral() != "99999999999999999999" {
+			t.Errorf("literal.TokenLiteral not %s. got=%s", "99999999999999999999", literal.TokenLiteral())
+		}
+	})
+
+	t.Run("float literal expression", func(t *testing.T) {
+		tests := []struct {
+			input    string
+			expected float64
+		}{
+			{"3.14;", 3.14},
+			{"1e-9;", 1e-9},
+			{"2.5E+3;", 2500},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.input, func(t *testing.T) {
+				l := lexer.New(tt.input)
+				p := New(l)
+				program := p.ParseProgram()
+				checkParserErrors(t, p)
+				checkProgramStatementsLength(t, program.Statements, 1)
+				stmt := checkStatementIsExpressionStatement(t, program.Statements[0])
+
+				literal, ok := stmt.Expression.(*ast.FloatLiteral)
+				if !ok {
+					t.Fatalf("exp not *ast.FloatLiteral. got=%T", stmt.Expression)
+				}
+				if literal.Value != tt.expected {
+					t.Errorf("literal.Value not %g. got=%g", tt.expected, literal.Value)
+				}
+			})
+		}
+	})
+
+	t.Run("string literal expression", func(t *testing.T) {
+		input := `"hello\tworld";`
+
+		l := lexer.New(input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+		checkProgramStatementsLength(t, program.Statements, 1)
+		stmt := checkStatementIsExpressionStatement(t, program.Statements[0])
+
+		literal, ok := stmt.Expression.(*ast.StringLiteral)
+		if !ok {
+			t.Fatalf("exp not *ast.StringLiteral. got=%T", stmt.Expression)
+		}
+		if literal.Value != "hello\tworld" {
+			t.Errorf("literal.Value not %q. got=%q", "hello\tworld", literal.Value)
+		}
+	})
+
+	t.Run("unterminated string literal", func(t *testing.T) {
+		input := `let s = "hello;`
+
+		l := lexer.New(input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) != 1 {
+			t.Fatalf("expected 1 error, got=%d (%v)", len(errors), errors)
+		}
+		if errors[0] != "1:9: unterminated string" {
+			t.Errorf("wrong error. got=%q", errors[0])
+		}
+	})
+
 	t.Run("prefix operator expressions", func(t *testing.T) {
 		prefixTests := []struct {
 			name     string
@@ -489,6 +771,51 @@ func TestExpressions(t *testing.T) {
 				"add(a + b + c * d / f + g)",
 				"add((((a + b) + ((c * d) / f)) + g))",
 			},
+			{
+				"index before mult",
+				"a * [1, 2, 3, 4][b * c] * d",
+				"((a * ([1, 2, 3, 4][(b * c)])) * d)",
+			},
+			{
+				"modulo with mult",
+				"a * b % c",
+				"((a * b) % c)",
+			},
+			{
+				"comparison before equality",
+				"a <= b == c >= d",
+				"((a <= b) == (c >= d))",
+			},
+			{
+				"equality before and",
+				"a == b && c != d",
+				"((a == b) && (c != d))",
+			},
+			{
+				"and before or",
+				"a || b && c || d",
+				"((a || (b && c)) || d)",
+			},
+			{
+				"assignment is lowest",
+				"x = a || b + 1",
+				"(x = (a || (b + 1)))",
+			},
+			{
+				"assignment is right associative",
+				"a = b += c",
+				"(a = (b += c))",
+			},
+			{
+				"index assignment",
+				"a[i * 2] = f(x)",
+				"((a[(i * 2)]) = f(x))",
+			},
+			{
+				"index in call",
+				"add(a * b[2], b[1], 2 * [1, 2][1])",
+				"add((a * (b[2])), (b[1]), (2 * ([1, 2][1])))",
+			},
 		}
 
 		for _, tt := range tests {
@@ -653,6 +980,133 @@ func TestExpressions(t *testing.T) {
 		}
 	})
 
+	t.Run("array literal", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			input    string
+			expected int
+		}{
+			{"empty", "[]", 0},
+			{"elements", "[1, 2 * 2, fn(x) { x }]", 3},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				l := lexer.New(tt.input)
+				p := New(l)
+				program := p.ParseProgram()
+				checkParserErrors(t, p)
+
+				checkProgramStatementsLength(t, program.Statements, 1)
+				stmt := checkStatementIsExpressionStatement(t, program.Statements[0])
+
+				array, ok := stmt.Expression.(*ast.ArrayLiteral)
+				if !ok {
+					t.Fatalf("exp not *ast.ArrayLiteral. got=%T", stmt.Expression)
+				}
+				if len(array.Elements) != tt.expected {
+					t.Fatalf("len(array.Elements) not %d. got=%d", tt.expected, len(array.Elements))
+				}
+			})
+		}
+	})
+
+	t.Run("hash literal", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			input    string
+			expected string
+		}{
+			{"empty", "{}", "{}"},
+			{"string keys", `{"one": 1, "two": 2, "three": 3}`, "{one: 1, two: 2, three: 3}"},
+			{"mixed keys", `{1: true, true: "yes"}`, "{1: true, true: yes}"},
+			{"expression values", `{"one": 0 + 1, "two": 10 - 8}`, "{one: (0 + 1), two: (10 - 8)}"},
+			{"hash in function body", `fn() { {"a": 1} }`, "fn(){a: 1}"},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				l := lexer.New(tt.input)
+				p := New(l)
+				program := p.ParseProgram()
+				checkParserErrors(t, p)
+
+				checkProgramStatementsLength(t, program.Statements, 1)
+				stmt := checkStatementIsExpressionStatement(t, program.Statements[0])
+
+				if stmt.Expression.String() != tt.expected {
+					t.Errorf("expected=%q, got=%q", tt.expected, stmt.Expression.String())
+				}
+			})
+		}
+	})
+
+	t.Run("hash literal pairs", func(t *testing.T) {
+		input := `{"one": 1, "two": 2}`
+
+		l := lexer.New(input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := checkStatementIsExpressionStatement(t, program.Statements[0])
+		hash, ok := stmt.Expression.(*ast.HashLiteral)
+		if !ok {
+			t.Fatalf("exp is not *ast.HashLiteral. got=%T", stmt.Expression)
+		}
+
+		expected := []struct {
+			key   string
+			value int64
+		}{{"one", 1}, {"two", 2}}
+
+		if len(hash.Pairs) != len(expected) {
+			t.Fatalf("hash.Pairs has wrong length. got=%d", len(hash.Pairs))
+		}
+
+		for i, pair := range hash.Pairs {
+			literal, ok := pair.Key.(*ast.StringLiteral)
+			if !ok {
+				t.Errorf("key is not *ast.StringLiteral. got=%T", pair.Key)
+				continue
+			}
+			if literal.Value != expected[i].key {
+				t.Errorf("key %d wrong. want=%q, got=%q", i, expected[i].key, literal.Value)
+			}
+			testIntegerLiteral(t, pair.Value, expected[i].value)
+		}
+	})
+
+	t.Run("malformed hash literal", func(t *testing.T) {
+		l := lexer.New(`{"one" 1}`)
+		p := New(l)
+		p.ParseProgram()
+
+		if len(p.Errors()) == 0 {
+			t.Errorf("expected parser errors, got none")
+		}
+	})
+
+	t.Run("index expression", func(t *testing.T) {
+		input := "myArray[1 + 1]"
+
+		l := lexer.New(input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := checkStatementIsExpressionStatement(t, program.Statements[0])
+		indexExp, ok := stmt.Expression.(*ast.IndexExpression)
+		if !ok {
+			t.Fatalf("exp not *ast.IndexExpression. got=%T", stmt.Expression)
+		}
+
+		if !testIdentifier(t, indexExp.Left, "myArray") {
+			return
+		}
+		testInfixExpression(t, indexExp.Index, 1, "+", 1)
+	})
+
 	t.Run("call expression", func(t *testing.T) {
 		input := "add(1, 2 * 3, 4 + 5)"
 		l := lexer.New(input)
@@ -707,6 +1161,12 @@ func TestExpressions(t *testing.T) {
 				"foo",
 				[]string{"1", "(2 * 3)", "(4 + 5)"},
 			},
+			{
+				"named params",
+				"foo(1, c: 2 * 3, b: x)",
+				"foo",
+				[]string{"1", "c: (2 * 3)", "b: x"},
+			},
 		}
 
 		for _, tt := range tests {
@@ -740,6 +1200,188 @@ func TestExpressions(t *testing.T) {
 		}
 	})
 
+	t.Run("default and rest parameters", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			input    string
+			expected string
+		}{
+			{"default value", "fn(a, b = 10) { a }", "fn(a, b = 10)a"},
+			{"default expression", "fn(a, b = a * 2, c = len(a)) { a }", "fn(a, b = (a * 2), c = len(a))a"},
+			{"rest parameter", "fn(...rest) { rest }", "fn(...rest)rest"},
+			{"everything", "fn(a, b = 1, ...rest) { a }", "fn(a, b = 1, ...rest)a"},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				p := New(lexer.New(tt.input))
+				program := p.ParseProgram()
+				checkParserErrors(t, p)
+
+				stmt := checkStatementIsExpressionStatement(t, program.Statements[0])
+				fn, ok := stmt.Expression.(*ast.FunctionLiteral)
+				if !ok {
+					t.Fatalf("fn not *ast.FunctionLiteral. got=%T", stmt.Expression)
+				}
+				if fn.String() != tt.expected {
+					t.Errorf("wrong function. want=%q, got=%q", tt.expected, fn.String())
+				}
+				if len(fn.Defaults) != len(fn.Parameters) {
+					t.Errorf("fn.Defaults does not match fn.Parameters. got=%d defaults for %d parameters", len(fn.Defaults), len(fn.Parameters))
+				}
+			})
+		}
+	})
+
+	t.Run("parameter and argument errors", func(t *testing.T) {
+		tests := []struct {
+			name          string
+			input         string
+			expectedError string
+		}{
+			{"duplicate parameter", "fn(a, b, a) { a }", "1:10: duplicate parameter a"},
+			{"duplicate rest parameter", "fn(a, ...a) { a }", "1:10: duplicate parameter a"},
+			{"required after default", "fn(a = 1, b) { a }", "1:11: parameter b without a default value follows one with a default"},
+			{"rest not last", "fn(...rest, a) { a }", "1:11: rest parameter rest must be the last parameter"},
+			{"parameter not an identifier", "fn(1) { 1 }", "1:4: expected next token to be IDENT, got INT instead"},
+			{"duplicate argument", "f(a: 1, a: 2)", "1:9: duplicate argument a"},
+			{"positional after named", "f(a: 1, 2)", "1:9: positional argument after named arguments"},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				p := New(lexer.New(tt.input))
+				p.ParseProgram()
+
+				errors := p.Errors()
+				if len(errors) == 0 || errors[0] != tt.expectedError {
+					t.Errorf("wrong errors. want first=%q, got=%q", tt.expectedError, errors)
+				}
+			})
+		}
+	})
+
+	t.Run("comments are ignored", func(t *testing.T) {
+		input := `// add them
+add(1, /* two */ 2) // done`
+
+		for _, keep := range []bool{false, true} {
+			l := lexer.New(input)
+			l.KeepComments(keep)
+			p := New(l)
+			program := p.ParseProgram()
+
+			checkParserErrors(t, p)
+			checkProgramStatementsLength(t, program.Statements, 1)
+
+			if program.String() != "add(1, 2)" {
+				t.Errorf("program wrong with KeepComments(%t). got=%q", keep, program.String())
+			}
+		}
+	})
+
+}
+
+func TestTailCalls(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []string // each call in the order it is written, as `function tail`
+	}{
+		{"last expression of a function", "fn() { f() }", []string{"f true"}},
+		{"return value", "fn() { if (x) { return f() } g(); 1 }", []string{"f true", "g false"}},
+		{"both branches of a final if", "fn() { if (x) { f() } else { g() } }", []string{"f true", "g true"}},
+		{"operand", "fn() { f() + 1 }", []string{"f false"}},
+		{"argument", "fn() { f(g()) }", []string{"f true", "g false"}},
+		{"let value", "fn() { let x = f(); x }", []string{"f false"}},
+		{"if that is not last", "fn() { if (x) { f() }; 1 }", []string{"f false"}},
+		{"inner function", "fn() { fn() { f() }; g() }", []string{"f true", "g true"}},
+		{"outside a function", "f(); return g()", []string{"f false", "g false"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := New(lexer.New(tt.input))
+			program := p.ParseProgram()
+			checkParserErrors(t, p)
+
+			calls := []string{}
+			var walk func(node ast.Node)
+			walk = func(node ast.Node) {
+				switch node := node.(type) {
+				case *ast.Program:
+					for _, stmt := range node.Statements {
+						walk(stmt)
+					}
+				case *ast.BlockStatement:
+					for _, stmt := range node.Statements {
+						walk(stmt)
+					}
+				case *ast.ExpressionStatement:
+					walk(node.Expression)
+				case *ast.ReturnStatement:
+					walk(node.ReturnValue)
+				case *ast.LetStatement:
+					walk(node.Value)
+				case *ast.InfixExpression:
+					walk(node.Left)
+					walk(node.Right)
+				case *ast.IfExpression:
+					walk(node.Consequence)
+					if node.Alternative != nil {
+						walk(node.Alternative)
+					}
+				case *ast.FunctionLiteral:
+					walk(node.Body)
+				case *ast.CallExpression:
+					calls = append(calls, fmt.Sprintf("%s %t", node.Function, node.Tail))
+					for _, arg := range node.Arguments {
+						walk(arg)
+					}
+				}
+			}
+			walk(program)
+
+			if !reflect.DeepEqual(calls, tt.expected) {
+				t.Errorf("wrong tail calls. want=%q, got=%q", tt.expected, calls)
+			}
+		})
+	}
+}
+
+func TestIncomplete(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected bool
+	}{
+		{"complete statement", "let x = 5;", false},
+		{"empty input", "", false},
+		{"let without value", "let x =", true},
+		{"trailing operator", "1 +", true},
+		{"unclosed function body", "fn(x) {", true},
+		{"unclosed parameters", "fn(x,", true},
+		{"unclosed call", "add(1, 2", true},
+		{"unclosed if block", "if (x) { x", true},
+		{"unclosed array", "[1, 2", true},
+		{"unterminated string", `"abc`, true},
+		{"brace inside string", `"{"`, false},
+		{"brace inside unterminated string", `puts("}`, true},
+		{"unterminated block comment", "1 /* one", true},
+		{"unexpected token", "let = 5;", false},
+		{"error before the end", "let = 5; fn(x) {", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := New(lexer.New(tt.input))
+			p.ParseProgram()
+
+			if p.Incomplete() != tt.expected {
+				t.Errorf("Incomplete() wrong. want=%t, got=%t (errors: %v)", tt.expected, p.Incomplete(), p.Errors())
+			}
+		})
+	}
 }
 
 func testIdentifier(t *testing.T, exp ast.Expression, value string) bool {
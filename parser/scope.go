@@ -0,0 +1,35 @@
+package parser
+
+// The parser keeps track of the names declared in each scope so that assignments to
+// constants and declarations that replace them are reported before the program runs.
+// Scopes mirror the environments the evaluator creates: one for the program, and one for
+// each function body and loop body.
+
+func (p *Parser) enterScope() {
+	p.scopes = append(p.scopes, map[string]bool{})
+}
+
+func (p *Parser) leaveScope() {
+	p.scopes = p.scopes[:len(p.scopes)-1]
+}
+
+// declare records name in the current scope, as a constant when constant is set.
+func (p *Parser) declare(name string, constant bool) {
+	p.scopes[len(p.scopes)-1][name] = constant
+}
+
+// isDeclaredConstant reports whether name is a constant in the current scope.
+func (p *Parser) isDeclaredConstant(name string) bool {
+	return p.scopes[len(p.scopes)-1][name]
+}
+
+// resolvesToConstant reports whether name refers to a constant, looking through the
+// scopes from the innermost one out.
+func (p *Parser) resolvesToConstant(name string) bool {
+	for i := len(p.scopes) - 1; i >= 0; i-- {
+		if constant, ok := p.scopes[i][name]; ok {
+			return constant
+		}
+	}
+	return false
+}
@@ -0,0 +1,31 @@
+package parser
+
+import "github.com/jacksonopp/monkey/ast"
+
+// Calls in tail position are marked so that the evaluator can make them without growing
+// the Go stack. A call is in tail position when its result is returned straight from the
+// function it is made in: it is the value of a return statement, or the last expression
+// of the function body, looking through the branches of an if expression.
+
+// markTailCalls marks the call that ends block, the body of a function or a branch of an
+// if expression in tail position.
+func markTailCalls(block *ast.BlockStatement) {
+	if block == nil || len(block.Statements) == 0 {
+		return
+	}
+
+	if stmt, ok := block.Statements[len(block.Statements)-1].(*ast.ExpressionStatement); ok {
+		markTailCall(stmt.Expression)
+	}
+}
+
+// markTailCall marks exp as a tail call, if it is a call, when it is in tail position.
+func markTailCall(exp ast.Expression) {
+	switch exp := exp.(type) {
+	case *ast.CallExpression:
+		exp.Tail = true
+	case *ast.IfExpression:
+		markTailCalls(exp.Consequence)
+		markTailCalls(exp.Alternative)
+	}
+}
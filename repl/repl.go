@@ -3,24 +3,51 @@ package repl
 import (
 	"bufio"
 	"fmt"
+	"github.com/jacksonopp/monkey/ast"
+	"github.com/jacksonopp/monkey/compiler"
 	"github.com/jacksonopp/monkey/evaluator"
 	"github.com/jacksonopp/monkey/lexer"
 	"github.com/jacksonopp/monkey/object"
 	"github.com/jacksonopp/monkey/parser"
 	"github.com/jacksonopp/monkey/token"
+	"github.com/jacksonopp/monkey/vm"
 	"io"
 )
 
 const PROMPT = ">> "
 
-func Start(in io.Reader, out io.Writer, flags [3]*bool) {
+// CONTINUATION_PROMPT is shown while the input so far is an incomplete statement.
+const CONTINUATION_PROMPT = ".. "
+
+// CANCEL discards an incomplete statement instead of continuing it.
+const CANCEL = ":cancel"
+
+// The engines that can execute programs.
+const (
+	EngineEval = "eval" // the tree-walking evaluator
+	EngineVM   = "vm"   // the bytecode compiler and virtual machine
+)
+
+func Start(in io.Reader, out io.Writer, flags [3]*bool, engine string) {
 	_, astFlag, tokenFlag := *flags[0], *flags[1], *flags[2]
 
 	scanner := bufio.NewScanner(in)
 	env := object.NewEnvironment()
 
+	// state kept between lines by the vm engine
+	constants := []object.Object{}
+	globals := make([]object.Object, vm.GlobalsSize)
+	symbolTable := compiler.NewSymbolTable()
+
+	// the lines of a statement that is not complete yet
+	pending := ""
+
 	for {
-		io.WriteString(out, PROMPT)
+		if pending == "" {
+			io.WriteString(out, PROMPT)
+		} else {
+			io.WriteString(out, CONTINUATION_PROMPT)
+		}
 		scanned := scanner.Scan()
 
 		if !scanned {
@@ -29,14 +56,24 @@ func Start(in io.Reader, out io.Writer, flags [3]*bool) {
 
 		line := scanner.Text()
 
-		if line == ":exit" {
+		if pending == "" && line == ":exit" {
 			io.WriteString(out, "Bye!\n")
 			return
 		}
 
-		l := lexer.New(line)
+		if pending != "" && line == CANCEL {
+			pending = ""
+			io.WriteString(out, "Input cancelled.\n")
+			continue
+		}
+
+		input := pending + line
+		pending = ""
+
+		l := lexer.New(input)
 
 		if tokenFlag {
+			l.KeepComments(true)
 			for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
 				t := fmt.Sprintf("%+v\n", tok)
 				io.WriteString(out, t)
@@ -48,6 +85,11 @@ func Start(in io.Reader, out io.Writer, flags [3]*bool) {
 
 		program := p.ParseProgram()
 
+		if p.Incomplete() {
+			pending = input + "\n"
+			continue
+		}
+
 		if len(p.Errors()) > 0 {
 			printParserErrors(out, p.Errors())
 			continue
@@ -59,10 +101,45 @@ func Start(in io.Reader, out io.Writer, flags [3]*bool) {
 			continue
 		}
 
-		evaluated := evaluator.Eval(program, env)
-		if evaluated != nil {
+		var evaluated object.Object
+
+		if engine == EngineVM {
+			comp := compiler.NewWithState(symbolTable, constants)
+			if err := comp.Compile(program); err != nil {
+				fmt.Fprintf(out, "Whoops! Compilation failed:\n\t%s\n", err)
+				continue
+			}
+
+			bytecode := comp.Bytecode()
+			constants = bytecode.Constants
+
+			machine := vm.NewWithGlobalsStore(bytecode, globals)
+			if err := machine.Run(); err != nil {
+				fmt.Fprintf(out, "Whoops! Executing bytecode failed:\n\t%s\n", err)
+				continue
+			}
+
+			evaluated = machine.LastPoppedStackElem()
+		} else {
+			evaluated = evaluator.Eval(program, env)
+		}
+
+		if errObj, ok := evaluated.(*object.Error); ok {
+			io.WriteString(out, errObj.Traceback())
+			io.WriteString(out, "\n")
+		} else if evaluated != nil && endsInExpression(program) {
 			io.WriteString(out, evaluated.Inspect())
 			io.WriteString(out, "\n")
 		}
 	}
 }
+
+// endsInExpression reports whether the last statement of program is an expression, whose
+// value is printed. Programs that end in a statement such as let print nothing.
+func endsInExpression(program *ast.Program) bool {
+	if len(program.Statements) == 0 {
+		return false
+	}
+	_, ok := program.Statements[len(program.Statements)-1].(*ast.ExpressionStatement)
+	return ok
+}
@@ -0,0 +1,93 @@
+package repl
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStart(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			"single line",
+			"1 + 2\n",
+			">> 3\n>> ",
+		},
+		{
+			"function across lines",
+			"fn(a, b) {\na + b\n}(1, 2)\n",
+			">> .. .. 3\n>> ",
+		},
+		{
+			"trailing operator",
+			"1 +\n2\n",
+			">> .. 3\n>> ",
+		},
+		{
+			"string across lines",
+			"\"a{\nb\"\n",
+			">> .. a{\nb\n>> ",
+		},
+		{
+			"cancel pending input",
+			"fn(x) {\n:cancel\n5\n",
+			">> .. Input cancelled.\n>> 5\n>> ",
+		},
+		{
+			"exit",
+			":exit\n5\n",
+			">> Bye!\n",
+		},
+	}
+
+	for _, engine := range []string{EngineEval, EngineVM} {
+		for _, tt := range tests {
+			t.Run(engine+"/"+tt.name, func(t *testing.T) {
+				var out strings.Builder
+				Start(strings.NewReader(tt.input), &out, newFlags(), engine)
+
+				if out.String() != tt.expected {
+					t.Errorf("wrong output. want=%q, got=%q", tt.expected, out.String())
+				}
+			})
+		}
+	}
+}
+
+func TestTraceback(t *testing.T) {
+	input := "let inner = fn(x) { x / 0 };\nlet outer = fn(x) { inner(x) };\nouter(1)\n"
+	expected := ">> >> >> Traceback (most recent call last):\n" +
+		"  1:6: in <program>, called `outer` with 1 argument\n" +
+		"  1:26: in `outer`, called `inner` with 1 argument\n" +
+		"ERROR: 1:23: division by zero\n>> "
+
+	var out strings.Builder
+	Start(strings.NewReader(input), &out, newFlags(), EngineEval)
+
+	if out.String() != expected {
+		t.Errorf("wrong output. want=%q, got=%q", expected, out.String())
+	}
+}
+
+func TestFailedDefinitionsInVM(t *testing.T) {
+	input := "let f = fn() { y };\nf()\nlet x = 1 / 0;\nx + 1\n"
+	expected := ">> Whoops! Compilation failed:\n\t1:16: identifier not found: y\n" +
+		">> ERROR: global used before it was assigned a value\n" +
+		">> ERROR: division by zero\n" +
+		">> ERROR: global used before it was assigned a value\n>> "
+
+	var out strings.Builder
+	Start(strings.NewReader(input), &out, newFlags(), EngineVM)
+
+	if out.String() != expected {
+		t.Errorf("wrong output. want=%q, got=%q", expected, out.String())
+	}
+}
+
+func newFlags() [3]*bool {
+	var user, ast, tokens bool
+	return [3]*bool{&user, &ast, &tokens}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"github.com/jacksonopp/monkey/compiler"
+	"github.com/jacksonopp/monkey/evaluator"
+	"github.com/jacksonopp/monkey/lexer"
+	"github.com/jacksonopp/monkey/object"
+	"github.com/jacksonopp/monkey/parser"
+	"github.com/jacksonopp/monkey/repl"
+	"github.com/jacksonopp/monkey/vm"
+	"io"
+	"os"
+)
+
+// runFile executes the Monkey program at path with the given engine, exposing args to
+// it as the `args` array. It returns the exit code for the process: non-zero when the
+// program fails to parse or evaluates to an error.
+func runFile(path string, args []string, engine string, stderr io.Writer) int {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(stderr, "monkey: %s\n", err)
+		return 1
+	}
+
+	l := lexer.NewFile(path, string(src))
+	p := parser.New(l)
+	program := p.ParseProgram()
+
+	if len(p.Errors()) > 0 {
+		for _, e := range p.Errors() {
+			fmt.Fprintln(stderr, e)
+		}
+		return 1
+	}
+
+	var evaluated object.Object
+
+	if engine == repl.EngineVM {
+		symbolTable := compiler.NewSymbolTable()
+		argsSymbol := symbolTable.Define("args")
+
+		comp := compiler.NewWithState(symbolTable, []object.Object{})
+		if err := comp.Compile(program); err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+
+		globals := make([]object.Object, vm.GlobalsSize)
+		globals[argsSymbol.Index] = scriptArgs(args)
+
+		machine := vm.NewWithGlobalsStore(comp.Bytecode(), globals)
+		if err := machine.Run(); err != nil {
+			fmt.Fprintf(stderr, "monkey: %s\n", err)
+			return 1
+		}
+		evaluated = machine.LastPoppedStackElem()
+	} else {
+		env := object.NewEnvironment()
+		env.Set("args", scriptArgs(args))
+
+		evaluated = evaluator.Eval(program, env)
+	}
+
+	if errObj, ok := evaluated.(*object.Error); ok {
+		fmt.Fprintln(stderr, errObj.Traceback())
+		return 1
+	}
+
+	return 0
+}
+
+// scriptArgs converts the command line arguments following the file name in to a Monkey array.
+func scriptArgs(args []string) *object.Array {
+	elements := make([]object.Object, len(args))
+	for i, arg := range args {
+		elements[i] = &object.String{Value: arg}
+	}
+	return &object.Array{Elements: elements}
+}
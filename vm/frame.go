@@ -0,0 +1,21 @@
+package vm
+
+import (
+	"github.com/jacksonopp/monkey/code"
+	"github.com/jacksonopp/monkey/object"
+)
+
+// Frame is the call frame of a closure being executed.
+type Frame struct {
+	cl          *object.Closure
+	ip          int // the instruction being executed
+	basePointer int // the bottom of the stack for this frame, where its locals start
+}
+
+func NewFrame(cl *object.Closure, basePointer int) *Frame {
+	return &Frame{cl: cl, ip: -1, basePointer: basePointer}
+}
+
+func (f *Frame) Instructions() code.Instructions {
+	return f.cl.Fn.Instructions
+}
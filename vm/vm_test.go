@@ -0,0 +1,458 @@
+package vm
+
+import (
+	"github.com/jacksonopp/monkey/ast"
+	"github.com/jacksonopp/monkey/compiler"
+	"github.com/jacksonopp/monkey/enginetest"
+	"github.com/jacksonopp/monkey/lexer"
+	"github.com/jacksonopp/monkey/object"
+	"github.com/jacksonopp/monkey/parser"
+	"testing"
+)
+
+type vmTestCase struct {
+	name     string
+	input    string
+	expected interface{}
+}
+
+// errorMessage is the expected message of an *object.Error result.
+type errorMessage string
+
+func TestIntegerArithmetic(t *testing.T) {
+	tests := []vmTestCase{
+		{"literal", "5", 5},
+		{"negative", "-5", -5},
+		{"addition", "5 + 5", 10},
+		{"multiplication", "5 * 5", 25},
+		{"precedence", "5 + 2 * 2", 9},
+		{"division and subtraction", "50 / 5 - 3", 7},
+		{"grouping", "2 * (3+4)", 14},
+		{"negative addition", "-30 + 10", -20},
+		{"negative grouping", "-(5 + 10)", -15},
+		{"modulo", "7 % 3 * 2", 2},
+		{"negative modulo", "-7 % 3", -1},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestBigIntegers(t *testing.T) {
+	tests := []vmTestCase{
+		{"literal", "99999999999999999999", bigInt("99999999999999999999")},
+		{"addition overflow", "9223372036854775807 + 1", bigInt("9223372036854775808")},
+		{"multiplication overflow", "4294967296 * 4294967296", bigInt("18446744073709551616")},
+		{"negation overflow", "-(-9223372036854775807 - 1)", bigInt("9223372036854775808")},
+		{"demotion", "9223372036854775808 - 1", 9223372036854775807},
+		{"negative literal", "-9223372036854775808", -9223372036854775807 - 1},
+		{"modulo", "99999999999999999999 % 10", 9},
+		{"mixed comparison", "1 < 99999999999999999999", true},
+		{"mixed equality", "9223372036854775807 + 1 == 9223372036854775808", true},
+		{"division by zero", "99999999999999999999 / 0", errorMessage("division by zero")},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestFloats(t *testing.T) {
+	tests := []vmTestCase{
+		{"literal", "3.14", inspected("3.14")},
+		{"exponent", "1e-9", inspected("1e-09")},
+		{"negative", "-1.5", inspected("-1.5")},
+		{"mixed addition", "1 + 0.5", inspected("1.5")},
+		{"mixed division", "7 / 2.0", inspected("3.5")},
+		{"modulo", "5.5 % 2", inspected("1.5")},
+		{"mixed comparison", "1.5 < 2", true},
+		{"mixed equality", "1.0 == 1", true},
+		{"division by zero", "1.0 / 0", inspected("+Inf")},
+		{"conversion", "int(3.9) + float(1)", inspected("4.0")},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestBooleanExpressions(t *testing.T) {
+	tests := []vmTestCase{
+		{"true", "true", true},
+		{"false", "false", false},
+		{"less than", "1 < 2", true},
+		{"greater than", "1 > 2", false},
+		{"equal", "1 == 1", true},
+		{"not equal", "1 != 1", false},
+		{"bools equal", "true == true", true},
+		{"bools not equal", "true != false", true},
+		{"comparison equals true", "(1 < 2) == true", true},
+		{"comparison equals false", "(1 > 2) == false", true},
+		{"not true", "!true", false},
+		{"not 5", "!5", false},
+		{"not not 5", "!!5", true},
+		{"not null", "!(if (false) { 5; })", true},
+		{"int is not bool", "1 == true", false},
+		{"less or equal", "1 <= 1", true},
+		{"greater or equal", "1 >= 2", false},
+		{"and", "1 < 2 && 2 < 3", true},
+		{"or", "1 > 2 || 2 > 3", false},
+		{"and before or", "true || false && false", true},
+		{"and returns right operand", "1 && 2", 2},
+		{"and returns falsy left operand", "false && 2", false},
+		{"or returns truthy left operand", "1 || 2", 1},
+		{"or returns right operand", "false || 2", 2},
+		{"or with null", `if (false) { 1 } || "default"`, "default"},
+		{"and short-circuits", "let f = fn() { 1 + true }; false && f()", false},
+		{"or short-circuits", "let f = fn() { 1 + true }; true || f()", true},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestStrings(t *testing.T) {
+	tests := []vmTestCase{
+		{"literal", `"Hello World!"`, "Hello World!"},
+		{"concatenation", `"Hello" + " " + "World!"`, "Hello World!"},
+		{"bound", `let greeting = "hi"; greeting + "!"`, "hi!"},
+		{"equal", `"monkey" == "monkey"`, true},
+		{"different", `"monkey" == "ape"`, false},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestConditionals(t *testing.T) {
+	tests := []vmTestCase{
+		{"true", "if (true) { 10 }", 10},
+		{"false", "if (false) { 10 }", NULL},
+		{"truthy", "if (1) { 10 }", 10},
+		{"comparison else", "if (1 > 10) { 10 } else { 20 }", 20},
+		{"comparison true", "if (1 < 10) { 10 } else { 20 }", 10},
+		{"condition is if", "if ((if (false) { 10 })) { 10 } else { 20 }", 20},
+		{"branch ends with let", "if (true) { let a = 5; }", NULL},
+		{"empty branch", "if (true) { }", NULL},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestGlobalLetStatements(t *testing.T) {
+	tests := []vmTestCase{
+		{"simple", "let a = 10; a;", 10},
+		{"calculation", "let a = 5 * 2; a;", 10},
+		{"multiple", "let a = 10; let b = a; b;", 10},
+		{"rebinding", "let a = 1; let a = a + 1; a;", 2},
+		{"multiple with calculations", "let a = 5; let b = 3; let c = a * b - 5; c;", 10},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestReturnStatements(t *testing.T) {
+	tests := []vmTestCase{
+		{"basic return", "return 10;", 10},
+		{"not post return", "return 10; 9;", 10},
+		{"not pre return or post return", "9; return 2 * 5; 9;", 10},
+		{
+			"inside a nested statement",
+			`
+			if (10 > 1) {
+			  if (10 > 1) {
+				return 10;
+			  }
+			  return 1;
+			}
+			`,
+			10,
+		},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestArraysAndHashes(t *testing.T) {
+	tests := []vmTestCase{
+		{"empty array", "[]", []int{}},
+		{"array literal", "[1, 2 * 2, 3 + 3]", []int{1, 4, 6}},
+		{"array index", "[1, 2, 3][1]", 2},
+		{"negative index", "[1, 2, 3][-1]", 3},
+		{"out of range", "[1, 2, 3][3]", NULL},
+		{"hash index", `{"one": 1, "two": 2}["two"]`, 2},
+		{"missing key", `{"one": 1}["two"]`, NULL},
+		{"computed hash index", `let key = "tw"; {"two": 2}[key + "o"]`, 2},
+		{"bool key", `{true: 5}[true]`, 5},
+		{"hash inspect", `{"b": 1, "a": 2}`, inspected("{b: 1, a: 2}")},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestFunctions(t *testing.T) {
+	tests := []vmTestCase{
+		{"identity", "let identity = fn(x) { x; }; identity(5);", 5},
+		{"identity with return", "let identity = fn(x) { return x; }; identity(5);", 5},
+		{"double", "let double = fn(x) { x * 2; }; double(5);", 10},
+		{"add", "let add = fn(x, y) { x + y; }; add(5, 5);", 10},
+		{"add with calling add", "let add = fn(x, y) { x + y; }; add(5, add(5, 5));", 15},
+		{"anonymous iife", "fn(x){ x; }(5);", 5},
+		{"empty body", "fn() { }();", NULL},
+		{"locals", "let f = fn() { let a = 1; let b = 2; a + b }; f();", 3},
+		{
+			"closure",
+			`
+let newAdder = fn(x) {
+  fn(y) { x + y };
+};
+
+let addTwo = newAdder(2);
+
+addTwo(2);
+`,
+			4,
+		},
+		{
+			"nested closures",
+			`
+let newAdder = fn(a, b) {
+	fn(c) { fn(d) { a + b + c + d } };
+};
+newAdder(1, 2)(3)(4);
+`,
+			10,
+		},
+		{
+			"recursive",
+			`
+let countDown = fn(x) { if (x == 0) { return 0; } else { countDown(x - 1); } };
+countDown(10);
+`,
+			0,
+		},
+		{
+			"recursive inside closure",
+			`
+let wrapper = fn() {
+	let fib = fn(x) { if (x < 2) { x } else { fib(x - 1) + fib(x - 2) } };
+	fib(15);
+};
+wrapper();
+`,
+			610,
+		},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestBuiltinFunctions(t *testing.T) {
+	tests := []vmTestCase{
+		{"len string", `len("four")`, 4},
+		{"len array", `len([1, 2, 3])`, 3},
+		{"len unsupported", `len(1)`, errorMessage("argument to `len` not supported, got INTEGER")},
+		{"first", `first([1, 2, 3])`, 1},
+		{"first empty", `first([])`, NULL},
+		{"rest", `rest([1, 2, 3])`, []int{2, 3}},
+		{"push", `push([], 1)`, []int{1}},
+		{"type of function", `type(fn() {})`, "FUNCTION"},
+		{"let shadows builtin", `let len = fn(x) { 42 }; len([])`, 42},
+		{"builtin as value", `let apply = fn(f, x) { f(x) }; apply(len, [1])`, 1},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestErrorHandling(t *testing.T) {
+	tests := []vmTestCase{
+		{"type mismatch", "5 + true", errorMessage("type mismatch: INTEGER + BOOLEAN")},
+		{"adding int and bool", "5 + true; 8;", errorMessage("type mismatch: INTEGER + BOOLEAN")},
+		{"negative true", "-true", errorMessage("unknown operator: -BOOLEAN")},
+		{"adding bools", "true + true", errorMessage("unknown operator: BOOLEAN + BOOLEAN")},
+		{"adding bools nested", "if (20 > 1) { true + false }", errorMessage("unknown operator: BOOLEAN + BOOLEAN")},
+		{
+			"adding bools deep nested",
+			`
+			if (10 > 1) {
+			  if (10 > 1) {
+				return true + false;
+			  }
+			return 1;
+			}
+			`,
+			errorMessage("unknown operator: BOOLEAN + BOOLEAN"),
+		},
+		{"subtracting strings", `"Hello" - "World"`, errorMessage("unknown operator: STRING - STRING")},
+		{"adding string and int", `"Hello" + 1`, errorMessage("type mismatch: STRING + INTEGER")},
+		{"division by zero", "1 / 0", errorMessage("division by zero")},
+		{"modulo by zero", "5 % (2 - 2)", errorMessage("modulo by zero")},
+		{"indexing an integer", "1[0]", errorMessage("index operator not supported: INTEGER[INTEGER]")},
+		{"function as hash key", `{"name": "Monkey"}[fn(x) { x }];`, errorMessage("unusable as hash key: FUNCTION")},
+		{"function as hash literal key", `{fn(x) { x }: "Monkey"}`, errorMessage("unusable as hash key: FUNCTION")},
+		{"calling an integer", "1()", errorMessage("not a function: INTEGER")},
+		{"error inside function", "let f = fn() { 1 + true; 5 }; f(); 10", errorMessage("type mismatch: INTEGER + BOOLEAN")},
+		{"wrong number of arguments", "fn(a, b) { a }(1)", errorMessage("wrong number of arguments: want=2, got=1")},
+		{"wrong number of arguments to a named function", "let add = fn(a, b) { a + b }; add(1)", errorMessage("wrong number of arguments to `add`: want=2, got=1")},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestStackOverflow(t *testing.T) {
+	program := parse("let f = fn(x) { f(x) + 1 }; f(1);")
+
+	comp := compiler.New()
+	if err := comp.Compile(program); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	vm := New(comp.Bytecode())
+	if err := vm.Run(); err == nil {
+		t.Fatalf("expected vm error, got none")
+	}
+}
+
+func TestGlobalOfFailedRun(t *testing.T) {
+	symbolTable := compiler.NewSymbolTable()
+	constants := []object.Object{}
+	globals := make([]object.Object, GlobalsSize)
+
+	// each program runs with the globals the ones before it left, like in the REPL
+	run := func(input string) object.Object {
+		comp := compiler.NewWithState(symbolTable, constants)
+		if err := comp.Compile(parse(input)); err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+		constants = comp.Bytecode().Constants
+
+		vm := NewWithGlobalsStore(comp.Bytecode(), globals)
+		vm.Run()
+		return vm.LastPoppedStackElem()
+	}
+
+	if result := run("let x = 1 / 0;"); result.Type() != object.ERROR_OBJ {
+		t.Fatalf("division by zero did not fail. got=%T (%+v)", result, result)
+	}
+
+	errObj, ok := run("x + 1").(*object.Error)
+	if !ok || errObj.Message != "global used before it was assigned a value" {
+		t.Errorf("wrong result. got=%+v", errObj)
+	}
+}
+
+// bigInt is the expected value of an *object.BigInt result, in decimal.
+type bigInt string
+
+// inspected is the expected Inspect output of a result.
+type inspected string
+
+func runVmTests(t *testing.T, tests []vmTestCase) {
+	t.Helper()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			program := parse(tt.input)
+
+			comp := compiler.New()
+			err := comp.Compile(program)
+			if err != nil {
+				t.Fatalf("compiler error: %s", err)
+			}
+
+			vm := New(comp.Bytecode())
+			err = vm.Run()
+			if err != nil {
+				t.Fatalf("vm error: %s", err)
+			}
+
+			testExpectedObject(t, tt.expected, vm.LastPoppedStackElem())
+		})
+	}
+}
+
+func parse(input string) *ast.Program {
+	l := lexer.New(input)
+	p := parser.New(l)
+	return p.ParseProgram()
+}
+
+func testExpectedObject(t *testing.T, expected interface{}, actual object.Object) {
+	t.Helper()
+
+	switch expected := expected.(type) {
+	case int:
+		testIntegerObject(t, int64(expected), actual)
+	case bool:
+		boolean, ok := actual.(*object.Boolean)
+		if !ok {
+			t.Fatalf("object is not Boolean. got=%T (%+v)", actual, actual)
+		}
+		if boolean.Value != expected {
+			t.Errorf("object has wrong value. want=%t, got=%t", expected, boolean.Value)
+		}
+	case string:
+		str, ok := actual.(*object.String)
+		if !ok {
+			t.Fatalf("object is not String. got=%T (%+v)", actual, actual)
+		}
+		if str.Value != expected {
+			t.Errorf("object has wrong value. want=%q, got=%q", expected, str.Value)
+		}
+	case []int:
+		array, ok := actual.(*object.Array)
+		if !ok {
+			t.Fatalf("object is not Array. got=%T (%+v)", actual, actual)
+		}
+		if len(array.Elements) != len(expected) {
+			t.Fatalf("wrong number of elements. want=%d, got=%d", len(expected), len(array.Elements))
+		}
+		for i, expectedElem := range expected {
+			testIntegerObject(t, int64(expectedElem), array.Elements[i])
+		}
+	case bigInt:
+		integer, ok := actual.(*object.BigInt)
+		if !ok {
+			t.Fatalf("object is not BigInt. got=%T (%+v)", actual, actual)
+		}
+		if integer.Value.String() != string(expected) {
+			t.Errorf("object has wrong value. want=%s, got=%s", expected, integer.Value)
+		}
+	case inspected:
+		if actual.Inspect() != string(expected) {
+			t.Errorf("wrong Inspect. want=%q, got=%q", expected, actual.Inspect())
+		}
+	case errorMessage:
+		errObj, ok := actual.(*object.Error)
+		if !ok {
+			t.Fatalf("object is not Error. got=%T (%+v)", actual, actual)
+		}
+		if errObj.Message != string(expected) {
+			t.Errorf("wrong error message. want=%q, got=%q", expected, errObj.Message)
+		}
+	case *object.Null:
+		if actual != NULL {
+			t.Errorf("object is not NULL. got=%T (%+v)", actual, actual)
+		}
+	}
+}
+
+func testIntegerObject(t *testing.T, expected int64, actual object.Object) {
+	t.Helper()
+
+	result, ok := actual.(*object.Integer)
+	if !ok {
+		t.Fatalf("object is not Integer. got=%T (%+v)", actual, actual)
+	}
+	if result.Value != expected {
+		t.Errorf("object has wrong value. want=%d, got=%d", expected, result.Value)
+	}
+}
+
+func TestEngineCases(t *testing.T) {
+	enginetest.Run(t, func(t *testing.T, input string) object.Object {
+		comp := compiler.New()
+		if err := comp.Compile(parse(input)); err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+
+		vm := New(comp.Bytecode())
+		if err := vm.Run(); err != nil {
+			t.Fatalf("vm error: %s", err)
+		}
+		return vm.LastPoppedStackElem()
+	})
+}